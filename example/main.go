@@ -6,7 +6,6 @@ import (
 	"github.com/razorcorp/go-routing-engine/gre"
 	"log"
 	"net/http"
-	"os"
 )
 
 /**
@@ -28,24 +27,6 @@ type Response struct {
 //	[]string{"path"},
 //)
 
-func appRecovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "fetal error, application unexpectedly exited: %s\n", err)
-				_, _ = fmt.Println("crash recovered")
-				w.WriteHeader(http.StatusInternalServerError)
-				response := gre.ErrorResponse{
-					Code:  http.StatusInternalServerError,
-					Cause: "oops, something went wrong. we're looking into it",
-				}
-				fmt.Fprint(w, response.Json())
-			}
-		}()
-		next.ServeHTTP(w, r)
-	})
-}
-
 func main() {
 
 	gre.RouteTable = append(gre.RouteTable, gre.Route{Name: "Hello",
@@ -56,13 +37,18 @@ func main() {
 	})
 
 	server := gre.DefaultServer(9999, false).
-		AddCORSHandler(gre.HttpResponseConfig{
-			ContextType:               "application/json",
-			AccessControlAllowOrigin:  "*",
-			AccessControlAllowMethods: []string{"POST", "GET", "OPTIONS", "PUT", "DELETE"},
-			AccessControlAllowHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+		UseCORS(gre.CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+		}).
+		UseResponseDefaults(gre.ResponseDefaults{ContentType: "application/json"}).
+		UseRecovery(gre.RecoveryConfig{
+			Response: &gre.ErrorResponse{
+				Code:  http.StatusInternalServerError,
+				Cause: "oops, something went wrong. we're looking into it",
+			},
 		}).
-		AddMiddleware(appRecovery)
+		UseRouteTable()
 	//AddRoutes(gre.Route{Name: "Hello",
 	//	Methods:     []string{http.MethodGet},
 	//	Pattern:     "/hello",
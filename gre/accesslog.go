@@ -0,0 +1,241 @@
+package gre
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessLog writes request logs either as slog records or as
+// lines formatted by an AccessLogFormat
+type accessLog struct {
+	logger *slog.Logger
+	format AccessLogFormat
+	out    io.Writer
+	mu     sync.Mutex
+
+	// requestIDHeader is read for the ID of requests
+	// served without the request ID middleware
+	requestIDHeader string
+}
+
+// CommonLogFormat formats the request in the Apache Common Log Format
+//
+// example: 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+func CommonLogFormat(entry *AccessLogEntry) string {
+	bytes := "-"
+	if entry.Bytes > 0 {
+		bytes = strconv.Itoa(entry.Bytes)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		dash(entry.RemoteIP),
+		dash(entry.User),
+		entry.Time.Format(clfTimeFormat),
+		entry.Method,
+		entry.URI,
+		entry.Proto,
+		entry.Status,
+		bytes,
+	)
+}
+
+// CombinedLogFormat formats the request in the Apache Combined Log Format,
+// the Common Log Format followed by the referer and user agent
+func CombinedLogFormat(entry *AccessLogEntry) string {
+	return fmt.Sprintf("%s %q %q", CommonLogFormat(entry), dash(entry.Referer), dash(entry.UserAgent))
+}
+
+// JSONLogFormat formats the request as a single line JSON object
+func JSONLogFormat(entry *AccessLogEntry) string {
+	body, err := json.Marshal(struct {
+		Time       string  `json:"time"`
+		RemoteIP   string  `json:"remote_ip"`
+		User       string  `json:"user,omitempty"`
+		Method     string  `json:"method"`
+		URI        string  `json:"uri"`
+		Path       string  `json:"path"`
+		Proto      string  `json:"proto"`
+		Route      string  `json:"route"`
+		Status     int     `json:"status"`
+		Bytes      int     `json:"bytes"`
+		DurationMs float64 `json:"duration_ms"`
+		Referer    string  `json:"referer,omitempty"`
+		UserAgent  string  `json:"user_agent,omitempty"`
+		RequestID  string  `json:"request_id,omitempty"`
+		TraceID    string  `json:"trace_id,omitempty"`
+		SpanID     string  `json:"span_id,omitempty"`
+	}{
+		Time:       entry.Time.Format(time.RFC3339Nano),
+		RemoteIP:   entry.RemoteIP,
+		User:       entry.User,
+		Method:     entry.Method,
+		URI:        entry.URI,
+		Path:       entry.Path,
+		Proto:      entry.Proto,
+		Route:      entry.Route,
+		Status:     entry.Status,
+		Bytes:      entry.Bytes,
+		DurationMs: float64(entry.Duration) / float64(time.Millisecond),
+		Referer:    entry.Referer,
+		UserAgent:  entry.UserAgent,
+		RequestID:  entry.RequestID,
+		TraceID:    entry.TraceID,
+		SpanID:     entry.SpanID,
+	})
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}", err.Error())
+	}
+	return string(body)
+}
+
+// TemplateLogFormat returns an AccessLogFormat that replaces the placeholders
+// in the template with the request values. Unknown placeholders are kept as is.
+//
+// placeholders: {time} {remote_ip} {user} {method} {uri} {path} {proto} {route}
+// {status} {bytes} {duration} {referer} {user_agent} {request_id} {trace_id} {span_id}
+//
+// param: <template> is the log line template
+// example: "{method} {path} {route} {status} {duration}"
+func TemplateLogFormat(template string) AccessLogFormat {
+	var (
+		literals []string
+		fields   []func(entry *AccessLogEntry) string
+	)
+
+	rest := template
+	for {
+		start := strings.Index(rest, "{")
+		length := strings.Index(rest[max(start, 0):], "}")
+		if start < 0 || length < 0 {
+			literals = append(literals, rest)
+			break
+		}
+		end := start + length
+
+		field, ok := placeholders[rest[start+1:end]]
+		if !ok {
+			literals = append(literals, rest[:end+1])
+			fields = append(fields, nil)
+		} else {
+			literals = append(literals, rest[:start])
+			fields = append(fields, field)
+		}
+		rest = rest[end+1:]
+	}
+
+	return func(entry *AccessLogEntry) string {
+		var line strings.Builder
+		for i, field := range fields {
+			line.WriteString(literals[i])
+			if field != nil {
+				line.WriteString(field(entry))
+			}
+		}
+		line.WriteString(literals[len(literals)-1])
+		return line.String()
+	}
+}
+
+var placeholders = map[string]func(entry *AccessLogEntry) string{
+	"time":       func(e *AccessLogEntry) string { return e.Time.Format(time.RFC3339) },
+	"remote_ip":  func(e *AccessLogEntry) string { return e.RemoteIP },
+	"user":       func(e *AccessLogEntry) string { return e.User },
+	"method":     func(e *AccessLogEntry) string { return e.Method },
+	"uri":        func(e *AccessLogEntry) string { return e.URI },
+	"path":       func(e *AccessLogEntry) string { return e.Path },
+	"proto":      func(e *AccessLogEntry) string { return e.Proto },
+	"route":      func(e *AccessLogEntry) string { return e.Route },
+	"status":     func(e *AccessLogEntry) string { return strconv.Itoa(e.Status) },
+	"bytes":      func(e *AccessLogEntry) string { return strconv.Itoa(e.Bytes) },
+	"duration":   func(e *AccessLogEntry) string { return e.Duration.String() },
+	"referer":    func(e *AccessLogEntry) string { return e.Referer },
+	"user_agent": func(e *AccessLogEntry) string { return e.UserAgent },
+	"request_id": func(e *AccessLogEntry) string { return e.RequestID },
+	"trace_id":   func(e *AccessLogEntry) string { return e.TraceID },
+	"span_id":    func(e *AccessLogEntry) string { return e.SpanID },
+}
+
+func newAccessLog(logger *slog.Logger, format AccessLogFormat, out io.Writer, requestIDHeader string) *accessLog {
+	if format != nil && out == nil {
+		out = os.Stdout
+	}
+	return &accessLog{logger: logger, format: format, out: out, requestIDHeader: requestIDHeader}
+}
+
+// entry returns the AccessLogEntry for a served request
+func (a *accessLog) entry(r *http.Request, recorder *responseRecorder, route string, start time.Time) *AccessLogEntry {
+	user, _, _ := r.BasicAuth()
+
+	traceID, spanID := spanIDs(r.Context())
+
+	return &AccessLogEntry{
+		Time:      start,
+		RemoteIP:  remoteIP(r),
+		User:      user,
+		Method:    r.Method,
+		URI:       r.RequestURI,
+		Path:      r.URL.Path,
+		Proto:     r.Proto,
+		Route:     route,
+		Status:    recorder.Status(),
+		Bytes:     recorder.bytes,
+		Duration:  time.Since(start),
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		RequestID: requestID(r, a.requestIDHeader),
+		TraceID:   traceID,
+		SpanID:    spanID,
+	}
+}
+
+// log writes the entry with the AccessLogFormat when set,
+// otherwise as a slog record
+func (a *accessLog) log(r *http.Request, entry *AccessLogEntry) {
+	if a.format != nil {
+		line := a.format(entry) + "\n"
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		_, _ = io.WriteString(a.out, line)
+		return
+	}
+
+	level := slog.LevelInfo
+	if entry.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("remote_ip", entry.RemoteIP),
+		slog.String("method", entry.Method),
+		slog.String("uri", entry.URI),
+		slog.String("route", entry.Route),
+		slog.Int("status", entry.Status),
+		slog.Int("bytes", entry.Bytes),
+		slog.Duration("duration", entry.Duration),
+		slog.String("user_agent", entry.UserAgent),
+	}
+	if entry.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", entry.RequestID))
+	}
+	if entry.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", entry.TraceID), slog.String("span_id", entry.SpanID))
+	}
+	a.logger.LogAttrs(r.Context(), level, "request", attrs...)
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
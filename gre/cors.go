@@ -0,0 +1,287 @@
+package gre
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var defaultCORSHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type", "Authorization"}
+
+// corsPolicy is a CORSConfig prepared for matching requests
+type corsPolicy struct {
+	config    CORSConfig
+	anyOrigin bool
+	origins   []string
+	wildcards [][2]string
+	patterns  []*regexp.Regexp
+	anyHeader bool
+	headers   map[string]bool
+	methods   map[string]bool
+}
+
+// corsPreflight answers preflight requests using the policy of the
+// route matching the requested method and path
+type corsPreflight struct {
+	router   *mux.Router
+	methods  []string
+	policies map[*mux.Route]*corsPolicy
+}
+
+// UseCORS enables Cross-Origin Resource Sharing for all the routes,
+// Route.CORS overrides the configuration of a single route
+//
+// param: <config> is CORSConfig definition of the allowed cross-origin requests
+func (s *Server) UseCORS(config CORSConfig) *Server {
+	s.config.cors = &config
+	return s
+}
+
+// WithCORS enables Cross-Origin Resource Sharing for the router routes
+//
+// param: <config> is CORSConfig definition of the allowed cross-origin requests
+func WithCORS(config CORSConfig) RouterOption {
+	return func(c *routerConfig) {
+		c.cors = &config
+	}
+}
+
+func newCORSPolicy(config CORSConfig) *corsPolicy {
+	p := &corsPolicy{config: config}
+
+	for _, origin := range config.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "*")
+			p.wildcards = append(p.wildcards, [2]string{scheme, host})
+		case origin != "":
+			p.origins = append(p.origins, origin)
+		}
+	}
+
+	// the patterns must match the whole origin
+	for _, pattern := range config.AllowedOriginPatterns {
+		p.patterns = append(p.patterns, regexp.MustCompile(`^(?:`+pattern.String()+`)$`))
+	}
+
+	headers := config.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	p.headers = make(map[string]bool, len(headers))
+	for _, header := range headers {
+		if header == "*" {
+			p.anyHeader = true
+		}
+		p.headers[strings.ToLower(header)] = true
+	}
+
+	if len(config.AllowedMethods) > 0 {
+		p.methods = make(map[string]bool, len(config.AllowedMethods))
+		for _, method := range config.AllowedMethods {
+			p.methods[strings.ToUpper(method)] = true
+		}
+	}
+	return p
+}
+
+// allowOrigin reports whether the origin may make cross-origin requests
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	for _, allowed := range p.origins {
+		if lower == allowed {
+			return true
+		}
+	}
+	for _, wildcard := range p.wildcards {
+		if len(lower) > len(wildcard[0])+len(wildcard[1]) &&
+			strings.HasPrefix(lower, wildcard[0]) && strings.HasSuffix(lower, wildcard[1]) {
+			return true
+		}
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowHeaders reports whether all the requested headers are allowed
+func (p *corsPolicy) allowHeaders(requested []string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range requested {
+		if !p.headers[strings.ToLower(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+// varyOrigin reports whether the response depends on the request origin
+func (p *corsPolicy) varyOrigin() bool {
+	return !p.anyOrigin || p.config.AllowCredentials
+}
+
+// setOrigin sets the response headers shared by preflight and actual requests
+func (p *corsPolicy) setOrigin(w http.ResponseWriter, origin string) {
+	if p.varyOrigin() {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	if p.config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// middleware adds the CORS headers to the route responses and
+// answers preflight requests of routes registered with OPTIONS
+func (p *corsPolicy) middleware(preflight *corsPreflight, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPreflight(r) && preflight.serve(w, r) {
+			return
+		}
+
+		if p.varyOrigin() {
+			w.Header().Add("Vary", "Origin")
+		}
+		if origin := r.Header.Get("Origin"); p.allowOrigin(origin) {
+			p.setOrigin(w, origin)
+			if len(p.config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.config.ExposedHeaders, ", "))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newCORSPreflight(router *mux.Router) *corsPreflight {
+	return &corsPreflight{router: router, policies: make(map[*mux.Route]*corsPolicy)}
+}
+
+// add registers the route methods and returns the CORS policy of the
+// mux route, nil when CORS is not enabled for the route
+func (c *corsPreflight) add(muxRoute *mux.Route, route Route, config *CORSConfig) *corsPolicy {
+	for _, method := range route.Methods {
+		method = strings.ToUpper(method)
+		if !slices.Contains(c.methods, method) {
+			c.methods = append(c.methods, method)
+		}
+	}
+
+	if route.CORS != nil {
+		config = route.CORS
+	}
+	// a CORSConfig allowing no origin disables CORS for the route
+	if config == nil || (len(config.AllowedOrigins) == 0 && len(config.AllowedOriginPatterns) == 0) {
+		return nil
+	}
+
+	policy := newCORSPolicy(*config)
+	c.policies[muxRoute] = policy
+	return policy
+}
+
+// handler answers preflight requests, other requests are served by next
+func (c *corsPreflight) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPreflight(r) && c.serve(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve answers the preflight request when the route matching the
+// requested method has CORS enabled, and reports whether it did
+func (c *corsPreflight) serve(w http.ResponseWriter, r *http.Request) bool {
+	requested := r.Header.Get("Access-Control-Request-Method")
+
+	var (
+		policy  *corsPolicy
+		methods []string
+	)
+	for _, method := range c.methods {
+		match := &mux.RouteMatch{}
+		req := r.Clone(r.Context())
+		req.Method = method
+		if !c.router.Match(req, match) || match.MatchErr != nil || match.Route == nil {
+			continue
+		}
+
+		methods = append(methods, method)
+		if method == requested {
+			policy = c.policies[match.Route]
+		}
+	}
+	if policy == nil {
+		return false
+	}
+
+	w.Header().Add("Vary", "Origin")
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	headers := requestedHeaders(r)
+	if !policy.allowOrigin(origin) || !policy.allowHeaders(headers) ||
+		(policy.methods != nil && !policy.methods[requested]) {
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+
+	allowed := methods[:0]
+	for _, method := range methods {
+		if policy.methods == nil || policy.methods[method] {
+			allowed = append(allowed, method)
+		}
+	}
+
+	policy.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if maxAge := policy.config.MaxAge; maxAge != 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(max(int(maxAge.Seconds()), 0)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// isPreflight reports whether the request is a CORS preflight request
+// rather than an OPTIONS request served by a route
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// requestedHeaders returns the Access-Control-Request-Headers list
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+	return headers
+}
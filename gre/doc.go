@@ -9,6 +9,15 @@ adding rotes and middleware. The main features are:
   - Set gre.Route structure for registering routes
   - Router and dispatcher built using github.com/gorilla/mux
   - Preconfigured http.Server option for hassle-free deployment
+  - Built-in panic recovery with error reporting hooks
+  - Request IDs propagated to the response, request logs and error responses
+  - OpenTelemetry server spans joining the caller trace, with trace IDs in logs and metric exemplars
+  - Route groups sharing a path prefix and group level middleware
+  - OpenAPI 3.1 document generated from the registered routes
+  - Liveness, readiness and startup probes with pluggable health checks
+  - Multiple TCP, Unix domain socket and systemd activated listeners per server
+  - Zero-downtime upgrades handing the listeners to a new process
+  - HTTPS with certificate reload, mutual TLS and HTTP/2 including cleartext h2c
   - Prometheus metrics built in with auto register to allow customer metrics registration
 
 Let's start building a simple HTTP server:
@@ -28,12 +37,12 @@ Let's start building a simple HTTP server:
 		})
 
 		server := gre.DefaultServer(9999, false).
-			AddCORSHandler(gre.HttpResponseConfig{
-				ContextType:               "application/json",
-				AccessControlAllowOrigin:  "*",
-				AccessControlAllowMethods: []string{"POST", "GET", "OPTIONS", "PUT", "DELETE"},
-				AccessControlAllowHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+			UseCORS(gre.CORSConfig{
+				AllowedOrigins: []string{"*"},
+				AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
 			}).
+			UseResponseDefaults(gre.ResponseDefaults{ContentType: "application/json"}).
+			UseRouteTable().
 			Build()
 
 		<-server.Start()
@@ -48,26 +57,26 @@ and strict slashes to false (if registered rote is `/path`, accessing `/path/` w
 
 	server := gre.DefaultServer(9999, false)
 
-Then we add a predefined middleware that sets the response structure for CORS queries. Following
+Then we enable CORS for any origin and set the default response content type. Following
 that we've the route definition and the request handler function and its code. This can be a reference
 to an actual function as long as the function resembles the http.HandlerFunc pattern.
+Routes added with AddRoutes belong to that server only, so multiple servers can run in the
+same process without sharing routes. Routes appended to gre.RouteTable are only served by
+servers that opt in with UseRouteTable().
 
 	server.
-		AddCORSHandler(gre.HttpResponseConfig{
-			ContextType:               "application/json",
-			AccessControlAllowOrigin:  "*",
-			AccessControlAllowMethods: []string{"POST", "GET", "OPTIONS", "PUT", "DELETE"},
-			AccessControlAllowHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+		UseCORS(gre.CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
 		}).
-		AddRoutes(gre.Routes{
-			gre.Route{Name:       "Hello",
-				Methods:    []string{http.MethodGet},
-				Pattern:    "/hello",
-				Deprecated: false,
-				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
-					w.WriteHeader(http.StatusOK)
-					fmt.Fprint(w, "{\"message\": \"hello\"}")
-				},
+		UseResponseDefaults(gre.ResponseDefaults{ContentType: "application/json"}).
+		AddRoutes(gre.Route{Name: "Hello",
+			Methods:    []string{http.MethodGet},
+			Pattern:    "/hello",
+			Deprecated: false,
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, "{\"message\": \"hello\"}")
 			},
 		})
 
@@ -82,5 +91,15 @@ syscall.SIGINT, syscall.SIGTERM which is used to invoke Stop() method to termina
 	if err := server.Stop(); err != nil {
 		log.Printf("%s", err.Error())
 	}
+
+Services that need to handle startup failures can use Run() instead, which returns bind errors
+to the caller and blocks until the context is done before stopping the server gracefully.
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Run(ctx); err != nil {
+		log.Printf("%s", err.Error())
+	}
 */
 package gre
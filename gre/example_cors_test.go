@@ -0,0 +1,132 @@
+package gre
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"time"
+)
+
+func ExampleServer_UseCORS() {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Method)
+	}
+
+	server := NewServer().
+		UseCORS(CORSConfig{
+			AllowedOrigins:        []string{"https://example.com", "https://*.example.com"},
+			AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`http://localhost:\d+`)},
+			AllowedHeaders:        []string{"Content-Type", "Authorization"},
+			ExposedHeaders:        []string{"X-Total-Count"},
+			AllowCredentials:      true,
+			MaxAge:                10 * time.Minute,
+		}).
+		AddRoutes(
+			Route{Name: "ListOrders", Methods: []string{http.MethodGet}, Pattern: "/orders", HandlerFunc: ok},
+			Route{Name: "CreateOrder", Methods: []string{http.MethodPost}, Pattern: "/orders", HandlerFunc: ok},
+			Route{Name: "OrderOptions", Methods: []string{http.MethodOptions}, Pattern: "/orders", HandlerFunc: ok},
+			Route{Name: "Public", Methods: []string{http.MethodGet}, Pattern: "/public", HandlerFunc: ok,
+				CORS: &CORSConfig{AllowedOrigins: []string{"*"}}},
+			Route{Name: "Internal", Methods: []string{http.MethodPut}, Pattern: "/internal", HandlerFunc: ok,
+				CORS: &CORSConfig{}},
+		).
+		Build()
+
+	do := func(method, path, origin string, headers ...string) {
+		r := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for i := 0; i < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, r)
+		fmt.Printf("%s %s %s: %d %q\n", method, path, origin, w.Code, w.Body.String())
+		for _, name := range []string{
+			"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Allow-Methods",
+			"Access-Control-Allow-Headers", "Access-Control-Max-Age", "Access-Control-Expose-Headers",
+		} {
+			if value := w.Header().Get(name); value != "" {
+				fmt.Printf("  %s: %s\n", name, value)
+			}
+		}
+		fmt.Printf("  Vary: %v\n", w.Header().Values("Vary"))
+	}
+
+	// preflight requests allow the methods registered for the path
+	do(http.MethodOptions, "/orders", "https://shop.example.com",
+		"Access-Control-Request-Method", http.MethodPost,
+		"Access-Control-Request-Headers", "content-type")
+
+	// preflight requests of unknown origins are answered without CORS headers
+	do(http.MethodOptions, "/orders", "https://example.org",
+		"Access-Control-Request-Method", http.MethodPost)
+
+	// OPTIONS requests that aren't preflight requests are served by the route
+	do(http.MethodOptions, "/orders", "")
+
+	// origin patterns match the whole origin
+	do(http.MethodGet, "/orders", "http://localhost:3000")
+	do(http.MethodGet, "/orders", "http://localhost:3000.example.org")
+	do(http.MethodGet, "/public", "https://example.org")
+
+	// an empty CORSConfig disables CORS for the route
+	do(http.MethodOptions, "/internal", "https://shop.example.com",
+		"Access-Control-Request-Method", http.MethodPut, "X-Request-ID", "preflight-1")
+
+	// Output:
+	// OPTIONS /orders https://shop.example.com: 204 ""
+	//   Access-Control-Allow-Origin: https://shop.example.com
+	//   Access-Control-Allow-Credentials: true
+	//   Access-Control-Allow-Methods: GET, POST, OPTIONS
+	//   Access-Control-Allow-Headers: content-type
+	//   Access-Control-Max-Age: 600
+	//   Vary: [Origin Access-Control-Request-Method Access-Control-Request-Headers]
+	// OPTIONS /orders https://example.org: 204 ""
+	//   Vary: [Origin Access-Control-Request-Method Access-Control-Request-Headers]
+	// OPTIONS /orders : 200 "OPTIONS"
+	//   Vary: [Origin]
+	// GET /orders http://localhost:3000: 200 "GET"
+	//   Access-Control-Allow-Origin: http://localhost:3000
+	//   Access-Control-Allow-Credentials: true
+	//   Access-Control-Expose-Headers: X-Total-Count
+	//   Vary: [Origin]
+	// GET /orders http://localhost:3000.example.org: 200 "GET"
+	//   Vary: [Origin]
+	// GET /public https://example.org: 200 "GET"
+	//   Access-Control-Allow-Origin: *
+	//   Vary: []
+	// OPTIONS /internal https://shop.example.com: 405 "{\"code\":405,\"cause\":\"method not allowed\",\"request_id\":\"preflight-1\"}"
+	//   Vary: []
+}
+
+func ExampleWithCORS() {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	// unnamed routes keep their own CORS configuration
+	router := NewRouter(Routes{
+		Route{Methods: []string{http.MethodPut}, Pattern: "/a", HandlerFunc: ok,
+			CORS: &CORSConfig{AllowedOrigins: []string{"https://a.example.com"}}},
+		Route{Methods: []string{http.MethodPut}, Pattern: "/b", HandlerFunc: ok,
+			CORS: &CORSConfig{AllowedOrigins: []string{"https://b.example.com"}}},
+	}, false, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithCORS(CORSConfig{}))
+
+	for _, path := range []string{"/a", "/b"} {
+		r := httptest.NewRequest(http.MethodOptions, path, nil)
+		r.Header.Set("Origin", "https://a.example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		fmt.Printf("%s %d %q\n", path, w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Output:
+	// /a 204 "https://a.example.com"
+	// /b 204 ""
+}
@@ -0,0 +1,112 @@
+package gre
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+)
+
+func ExampleServer_AddHealthCheck() {
+	server := NewServer().
+		UseProbes(ProbesConfig{}).
+		AddHealthCheck(HealthCheck{
+			Name:     "database",
+			Critical: true,
+			Check: func(ctx context.Context) error {
+				return nil
+			},
+		}).
+		AddHealthCheck(HealthCheck{
+			Name: "cache",
+			Check: func(ctx context.Context) error {
+				return errors.New("connection refused")
+			},
+		}).
+		AddHealthCheck(HealthCheck{
+			Name:     "disk space",
+			Critical: true,
+			Probes:   []Probe{LivenessProbe, ReadinessProbe},
+			Check: func(ctx context.Context) error {
+				return nil
+			},
+		}).
+		AddHealthCheck(HealthCheck{
+			Name: "queue",
+			Check: func(ctx context.Context) error {
+				panic("nil consumer")
+			},
+		}).
+		Build()
+
+	for _, path := range []string{"/livez", "/readyz", "/startupz"} {
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Println(path, w.Body.String())
+	}
+
+	// verbose mode adds the check errors and durations
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil))
+
+	var resp response
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	for _, check := range resp.Checks {
+		fmt.Printf("%s %s %q %t\n", check.Name, check.Status, check.Error, check.Duration != "")
+	}
+
+	// Output:
+	// /livez {"code":200,"status":"Live","checks":[{"name":"disk space","status":"pass","critical":true}]}
+	// /readyz {"code":200,"status":"Ready","checks":[{"name":"database","status":"pass","critical":true},{"name":"cache","status":"fail","critical":false},{"name":"disk space","status":"pass","critical":true},{"name":"queue","status":"fail","critical":false}]}
+	// /startupz {"code":503,"status":"Starting"}
+	// database pass "" true
+	// cache fail "connection refused" true
+	// disk space pass "" true
+	// queue fail "check panicked: nil consumer" true
+}
+
+func ExampleServer_UseProbes() {
+	server := NewServer().
+		UseProbes(ProbesConfig{LivenessPath: "/healthz/live"}).
+		AddRoutes(Route{Name: "Ready",
+			Methods: []string{http.MethodGet},
+			Pattern: "/readyz",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "served by the application")
+			},
+		})
+
+	// the built-in readiness probe is skipped as its path is in use
+	for _, route := range server.Routes() {
+		fmt.Println(route.Name, route.Pattern)
+	}
+
+	// Output:
+	// Health /health
+	// Liveness /healthz/live
+	// Startup /startupz
+	// Ready /readyz
+}
+
+func ExampleServer_UseProbes_nameConflict() {
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		UseProbes(ProbesConfig{}).
+		AddRoutes(Route{Name: "Health",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/api/health",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		})
+	server.Addr = "127.0.0.1:0"
+
+	// built-in routes are only skipped for their path, not their name
+	_, err := server.Listen()
+	fmt.Println(err)
+
+	// Output:
+	// route conflict: name "Health" is already registered
+}
@@ -0,0 +1,64 @@
+package gre
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"golang.org/x/net/http2"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+func ExampleServer_UseHTTP2() {
+	reserved, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := reserved.Addr().String()
+	_ = reserved.Close()
+
+	server := NewServer().
+		UseHTTP2(HTTP2Config{
+			H2C:                  true,
+			MaxConcurrentStreams: 100,
+			MaxReadFrameSize:     1 << 20,
+			IdleTimeout:          time.Minute,
+		}).
+		AddRoutes(Route{
+			Name:    "Proto",
+			Methods: []string{http.MethodGet},
+			Pattern: "/proto",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintln(w, r.Proto)
+			},
+		})
+	server.Addr = addr
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer server.Stop()
+
+	// the client speaks HTTP/2 with prior knowledge over a plain TCP connection
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+
+	resp, err := client.Get("http://" + addr + "/proto")
+	if err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	var served string
+	_, _ = fmt.Fscanln(resp.Body, &served)
+	fmt.Println(resp.Proto, served)
+
+	// Output:
+	// HTTP/2.0 HTTP/2.0
+}
@@ -0,0 +1,62 @@
+package gre
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func ExampleServer_AddListener() {
+	dir, _ := os.MkdirTemp("", "gre-listener")
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "gre.sock")
+
+	server := NewServer().
+		AddListener(ListenerConfig{Network: "tcp", Address: "127.0.0.1:0"}).
+		AddListener(ListenerConfig{Network: "unix", Address: socket, Mode: 0660})
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer server.Stop()
+
+	info, _ := os.Stat(socket)
+	fmt.Println("socket mode:", info.Mode().Perm())
+
+	get := func(client *http.Client, url string) string {
+		resp, err := client.Get(url)
+		if err != nil {
+			return err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	for _, addr := range server.Addrs() {
+		switch addr.Network() {
+		case "tcp":
+			fmt.Println("tcp:", get(http.DefaultClient, "http://"+addr.String()+"/health"))
+		case "unix":
+			fmt.Println("unix:", get(unixClient, "http://gre/health"))
+		}
+	}
+
+	// Output:
+	// socket mode: -rw-rw----
+	// tcp: {"code":200,"status":"Alive"}
+	// unix: {"code":200,"status":"Alive"}
+}
@@ -0,0 +1,98 @@
+package gre
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+)
+
+func ExampleLogger() {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	handler := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "{\"id\":1}")
+	}), "CreateUser")
+
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.Header.Set("User-Agent", "example/1.0")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	// the final status is logged after informational responses
+	hints := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusNoContent)
+	}), "EarlyHints")
+	hints.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	// requests of handlers that panic are logged before the panic continues
+	crash := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("fake application crash")
+	}), "Crash")
+	func() {
+		defer func() {
+			fmt.Println("recovered:", recover())
+		}()
+		crash.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/crash", nil))
+	}()
+
+	// Output:
+	// level=INFO msg=request remote_ip=192.0.2.1 method=POST uri=/users route=CreateUser status=201 bytes=8 user_agent=example/1.0
+	// level=INFO msg=request remote_ip=192.0.2.1 method=GET uri=/page route=EarlyHints status=204 bytes=0 user_agent=""
+	// level=ERROR msg=request remote_ip=192.0.2.1 method=GET uri=/crash route=Crash status=500 bytes=0 user_agent=""
+	// recovered: fake application crash
+}
+
+func ExampleCombinedLogFormat() {
+	entry := &AccessLogEntry{
+		Time:      time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60)),
+		RemoteIP:  "127.0.0.1",
+		User:      "frank",
+		Method:    http.MethodGet,
+		URI:       "/apache_pb.gif",
+		Proto:     "HTTP/1.0",
+		Status:    http.StatusOK,
+		Bytes:     2326,
+		Referer:   "http://www.example.com/start.html",
+		UserAgent: "Mozilla/4.08 [en] (Win98; I ;Nav)",
+	}
+
+	fmt.Println(CommonLogFormat(entry))
+	fmt.Println(CombinedLogFormat(entry))
+
+	// Output:
+	// 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+	// 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"
+}
+
+func ExampleServer_UseAccessLog() {
+	server := NewServer().
+		UseAccessLog(TemplateLogFormat("{method} {path} {route} {status} {bytes} {request_id} {unknown}"), os.Stdout).
+		AddRoutes(Route{Name: "GetUser",
+			Methods: []string{http.MethodGet},
+			Pattern: "/user/{name}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "{\"name\":\"frank\"}")
+			},
+		}).
+		Build()
+
+	r := httptest.NewRequest(http.MethodGet, "/user/frank", nil)
+	r.Header.Set("X-Request-ID", "4bf92f35")
+	server.Handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	// Output:
+	// GET /user/frank GetUser 200 16 4bf92f35 {unknown}
+}
@@ -0,0 +1,153 @@
+package gre
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func Example_metricsLabels() {
+	router := NewRouter(Routes{
+		Route{Name: "GetOrder",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/orders/{id}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		},
+	}, false)
+
+	for _, path := range []string{"/orders/1", "/orders/2", "/orders/3", "/wp-admin.php"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	families, _ := prometheus.DefaultGatherer.Gather()
+	for _, family := range families {
+		if family.GetName() != "http_response_time_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == "/orders/{id}" {
+				fmt.Println(labels["route"], labels["method"], labels["code"], metric.GetHistogram().GetSampleCount())
+			}
+			if labels["route"] == unmatchedRoute && labels["code"] == "404" {
+				fmt.Println(labels["route"], labels["method"], labels["code"])
+			}
+		}
+	}
+
+	// Output:
+	// /orders/{id} GET 200 3
+	// unmatched GET 404
+}
+
+func ExampleServer_UseMetrics() {
+	server := NewServer().
+		UseMetrics(MetricsConfig{
+			Namespace:       "shop",
+			DurationBuckets: []float64{0.05, 0.1, 0.5, 1},
+		}).
+		AddRoutes(Route{Name: "ListProducts",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/products",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		}).
+		Build()
+
+	server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+
+	families, _ := prometheus.DefaultGatherer.Gather()
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "shop_") {
+			continue
+		}
+		fmt.Println(family.GetName(), family.GetType())
+		if family.GetName() == "shop_http_requests_total" {
+			for _, label := range family.GetMetric()[0].GetLabel() {
+				fmt.Printf("  %s=%s\n", label.GetName(), label.GetValue())
+			}
+		}
+	}
+
+	// Output:
+	// shop_http_request_size_bytes HISTOGRAM
+	// shop_http_requests_in_flight GAUGE
+	// shop_http_requests_total COUNTER
+	//   class=2xx
+	//   code=200
+	//   method=GET
+	//   route=/products
+	// shop_http_response_size_bytes HISTOGRAM
+	// shop_http_response_time_seconds HISTOGRAM
+}
+
+func ExampleMetricsConfig_registry() {
+	registry := prometheus.NewRegistry()
+
+	server := NewServer().
+		UseMetrics(MetricsConfig{
+			Path:       "/internal/metrics",
+			Registerer: registry,
+		}).
+		Build()
+
+	server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	for _, path := range []string{"/metrics", "/internal/metrics"} {
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Println(path, w.Code, strings.Contains(w.Body.String(), `http_requests_total{class="2xx",code="200",method="GET",route="/health"} 1`))
+	}
+
+	// Output:
+	// /metrics 404 false
+	// /internal/metrics 200 true
+}
+
+func ExampleMetricsConfig_shared() {
+	registry := prometheus.NewRegistry()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// the second router reuses the metrics registered by the first one
+	for i := 0; i < 2; i++ {
+		router := NewRouter(Routes{{Name: "Products", Methods: []string{http.MethodGet}, Pattern: "/products",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {}}},
+			false, WithLogger(logger), WithMetrics(MetricsConfig{Registerer: registry, Subsystem: "api"}))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+	}
+
+	families, _ := registry.Gather()
+	for _, family := range families {
+		if family.GetName() == "api_http_requests_total" {
+			fmt.Println(family.GetName(), family.GetMetric()[0].GetCounter().GetValue())
+		}
+	}
+
+	// Output:
+	// api_http_requests_total 2
+}
+
+// registerer only implements prometheus.Registerer
+type registerer struct {
+	prometheus.Registerer
+}
+
+func ExampleMetricsConfig_gatherer() {
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		UseMetrics(MetricsConfig{Registerer: registerer{prometheus.NewRegistry()}})
+	server.Addr = "127.0.0.1:0"
+
+	// the metrics endpoint can't serve the metrics of the Registerer
+	_, err := server.Listen()
+	fmt.Println(err)
+
+	// Output:
+	// metrics: MetricsConfig.Gatherer is required when the Registerer is not a prometheus.Gatherer
+}
@@ -0,0 +1,46 @@
+package gre
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+func ExampleServer_UseProblemDetails() {
+	server := NewServer().
+		UseProblemDetails().
+		Build()
+
+	r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	r.Header.Set("X-Request-ID", "4bf92f35")
+
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, r)
+
+	fmt.Println(w.Code, w.Header().Get("Content-Type"))
+	fmt.Println(w.Body.String())
+
+	// Output:
+	// 404 application/problem+json
+	// {"detail":"resource not found","instance":"/missing","request_id":"4bf92f35","status":404,"title":"Not Found","type":"about:blank"}
+}
+
+func ExampleWriteProblem() {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		problem := NewProblem(http.StatusForbidden, "your current balance is 30, but that costs 50")
+		problem.Type = "https://example.com/probs/out-of-credit"
+		problem.Title = "You do not have enough credit."
+		problem.Extensions = map[string]interface{}{"balance": 30}
+		WriteProblem(w, r, problem)
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/account/12345/msgs/abc", nil))
+
+	fmt.Println(w.Code, w.Header().Get("Content-Type"))
+	fmt.Println(w.Body.String())
+
+	// Output:
+	// 403 application/problem+json
+	// {"balance":30,"detail":"your current balance is 30, but that costs 50","instance":"/account/12345/msgs/abc","status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}
+}
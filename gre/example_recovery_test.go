@@ -0,0 +1,102 @@
+package gre
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+)
+
+func ExampleServer_UseRecovery() {
+	registry := prometheus.NewRegistry()
+
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		UseMetrics(MetricsConfig{Registerer: registry}).
+		UseRecovery(RecoveryConfig{
+			Response: &ErrorResponse{
+				Code:  http.StatusServiceUnavailable,
+				Cause: "oops, something went wrong. we're looking into it",
+			},
+			Reporter: func(r *http.Request, report *PanicReport) {
+				fmt.Printf("reported: route=%s panic=%v stack=%t\n", report.Route, report.Value, len(report.Stack) > 0)
+			},
+		}).
+		AddRoutes(
+			Route{Name: "Crash", Methods: []string{http.MethodGet}, Pattern: "/crash",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					panic("fake application crash")
+				}},
+			Route{Name: "Partial", Methods: []string{http.MethodGet}, Pattern: "/partial",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+					panic("crash after the response header")
+				}},
+		).
+		AddGroups(RouteGroup{
+			Prefix: "/admin",
+			Middlewares: []func(http.Handler) http.Handler{
+				func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						panic("group middleware crash")
+					})
+				},
+			},
+			Routes: Routes{
+				Route{Name: "Users", Methods: []string{http.MethodGet}, Pattern: "/users",
+					HandlerFunc: func(w http.ResponseWriter, r *http.Request) {}},
+			},
+		}).
+		Build()
+
+	r := httptest.NewRequest(http.MethodGet, "/crash", nil)
+	r.Header.Set("X-Request-ID", "4bf92f35")
+
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, r)
+	fmt.Println(w.Code, w.Body.String())
+
+	// the connection is aborted once the response header has been written
+	func() {
+		defer func() {
+			fmt.Println("aborted:", recover() == http.ErrAbortHandler)
+		}()
+		server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/partial", nil))
+	}()
+
+	// panics in the group and route middleware are recovered too
+	w = httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	fmt.Println(w.Code)
+
+	families, _ := registry.Gather()
+	for _, family := range families {
+		if name := family.GetName(); name == "http_panics_total" || name == "http_requests_total" {
+			for _, metric := range family.GetMetric() {
+				line := []interface{}{name}
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "route" || label.GetName() == "code" {
+						line = append(line, label.GetValue())
+					}
+				}
+				fmt.Println(append(line, metric.GetCounter().GetValue())...)
+			}
+		}
+	}
+
+	// Output:
+	// reported: route=Crash panic=fake application crash stack=true
+	// 503 {"code":503,"cause":"oops, something went wrong. we're looking into it","request_id":"4bf92f35"}
+	// reported: route=Partial panic=crash after the response header stack=true
+	// aborted: true
+	// reported: route=Users panic=group middleware crash stack=true
+	// 503
+	// http_panics_total /admin/users 1
+	// http_panics_total /crash 1
+	// http_panics_total /partial 1
+	// http_requests_total 200 /partial 1
+	// http_requests_total 503 /admin/users 1
+	// http_requests_total 503 /crash 1
+}
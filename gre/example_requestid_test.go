@@ -0,0 +1,75 @@
+package gre
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+)
+
+func ExampleServer_UseRequestID() {
+	var generated int
+	server := NewServer().
+		UseRequestID(RequestIDConfig{
+			Header: "X-Correlation-ID",
+			Generate: func() string {
+				generated++
+				return "generated-" + strconv.Itoa(generated)
+			},
+		}).
+		AddRoutes(Route{Name: "Echo",
+			Methods: []string{http.MethodGet},
+			Pattern: "/echo",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, RequestIDFromContext(r.Context()))
+			},
+		}).
+		Build()
+
+	for _, id := range []string{"4bf92f35", "", "not a valid id"} {
+		r := httptest.NewRequest(http.MethodGet, "/echo", nil)
+		if id != "" {
+			r.Header.Set("X-Correlation-ID", id)
+		}
+
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, r)
+		fmt.Printf("%q: %s %s\n", id, w.Body.String(), w.Header().Get("X-Correlation-ID"))
+	}
+
+	// error responses include the request ID
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	fmt.Println(w.Body.String())
+
+	// Output:
+	// "4bf92f35": 4bf92f35 4bf92f35
+	// "": generated-1 generated-1
+	// "not a valid id": generated-2 generated-2
+	// {"code":404,"cause":"resource not found","request_id":"generated-3"}
+}
+
+func ExampleRequestIDConfig_disabled() {
+	// the IDs set by a proxy in front of the server are reported as is
+	server := NewServer().
+		UseRequestID(RequestIDConfig{Disabled: true, Header: "X-Correlation-ID"}).
+		Build()
+
+	r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	r.Header.Set("X-Correlation-ID", "from-proxy")
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, r)
+	fmt.Println(w.Body.String(), w.Header().Get("X-Correlation-ID") == "")
+
+	// Output:
+	// {"code":404,"cause":"resource not found","request_id":"from-proxy"} true
+}
+
+func ExampleNewRequestID() {
+	uuidv7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	fmt.Println(uuidv7.MatchString(NewRequestID()))
+
+	// Output:
+	// true
+}
@@ -0,0 +1,46 @@
+package gre
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+func ExampleServer_UseResponseDefaults() {
+	server := NewServer().
+		UseResponseDefaults(ResponseDefaults{
+			ContentType: "application/json",
+			Charset:     "utf-8",
+			Headers:     http.Header{"X-Content-Type-Options": {"nosniff"}},
+		}).
+		AddRoutes(
+			Route{Name: "Hello", Methods: []string{http.MethodGet}, Pattern: "/hello",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					_, _ = fmt.Fprint(w, `{"message":"hello"}`)
+				}},
+			Route{Name: "Report", Methods: []string{http.MethodGet}, Pattern: "/report", ContentType: "text/csv",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					_, _ = fmt.Fprint(w, "id,total\n1,10\n")
+				}},
+			Route{Name: "Image", Methods: []string{http.MethodGet}, Pattern: "/image",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "image/png")
+					w.WriteHeader(http.StatusOK)
+				}},
+			Route{Name: "Old", Methods: []string{http.MethodGet}, Pattern: "/old", Deprecated: true},
+		).
+		Build()
+
+	for _, path := range []string{"/hello", "/report", "/image", "/old", "/missing"} {
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Println(path, w.Code, w.Header().Get("Content-Type"), w.Header().Get("X-Content-Type-Options"))
+	}
+
+	// Output:
+	// /hello 200 application/json; charset=utf-8 nosniff
+	// /report 200 text/csv; charset=utf-8 nosniff
+	// /image 200 image/png nosniff
+	// /old 403 application/json; charset=utf-8 nosniff
+	// /missing 404 application/json; charset=utf-8 nosniff
+}
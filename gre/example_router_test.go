@@ -1,7 +1,10 @@
 package gre
 
 import (
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 )
 
 /**
@@ -25,13 +28,54 @@ func ExampleNewRouter() {
 
 	log.Printf("%#v", router)
 
-	// Output:
-	// 2023/05/01 13:02:45 add global handler 404 - not found
-	// 2023/05/01 13:02:45 add global handler 405 - method not allowed
-	// 2023/05/01 13:02:45 add mapping: Hello ( [GET POST] /hello )
+	// Log output:
+	// 2023/05/01 13:02:45 INFO add global handler code=404 handler="not found"
+	// 2023/05/01 13:02:45 INFO add global handler code=405 handler="method not allowed"
+	// 2023/05/01 13:02:45 INFO add mapping name=Hello methods=[GET POST] pattern=/hello
 	// 2023/05/01 13:02:45 &mux.Router{NotFoundHandler:(http.HandlerFunc)(0xc97580), MethodNotAllowedHandler:(http.HandlerFunc)(0xc97680),
 	// routes:[]*mux.Route{(*mux.Route)(0xc0002141e0), (*mux.Route)(0xc0002143c0)}, namedRoutes:map[string]*mux.Route{"Hello":(*mux.Route)(0xc0002141e0)},
 	// KeepContext:false, middlewares:[]mux.middleware{(mux.MiddlewareFunc)(0xc76c80), (mux.MiddlewareFunc)(0xc96b80)},
 	// routeConf:mux.routeConf{useEncodedPath:false, strictSlash:false, skipClean:false, regexp:mux.routeRegexpGroup{host:(*mux.routeRegexp)(nil),
 	// path:(*mux.routeRegexp)(nil), queries:[]*mux.routeRegexp(nil)}, matchers:[]mux.matcher(nil), buildScheme:"", buildVarsFunc:(mux.BuildVarsFunc)(nil)}}
 }
+
+func ExampleRoute_middlewares() {
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Println("enter", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	routes := Routes{
+		Route{
+			Name:        "Admin",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/admin",
+			Middlewares: []func(http.Handler) http.Handler{trace("admin only"), trace("size limit")},
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Println("handle admin")
+			},
+		},
+		Route{
+			Name:    "Public",
+			Methods: []string{http.MethodGet},
+			Pattern: "/public",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Println("handle public")
+			},
+		},
+	}
+	router := NewRouter(routes, false)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+
+	// Output:
+	// enter admin only
+	// enter size limit
+	// handle admin
+	// handle public
+}
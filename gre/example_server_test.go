@@ -1,10 +1,20 @@
 package gre
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -18,12 +28,11 @@ import (
 func ExampleDefaultServer() {
 
 	server := DefaultServer(9999, false).
-		AddCORSHandler(HttpResponseConfig{
-			ContextType:               "application/json",
-			AccessControlAllowOrigin:  "*",
-			AccessControlAllowMethods: []string{"POST", "GET", "OPTIONS", "PUT", "DELETE"},
-			AccessControlAllowHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+		UseCORS(CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
 		}).
+		UseResponseDefaults(ResponseDefaults{ContentType: "application/json"}).
 		AddRoutes(Route{Name: "Hello",
 			Methods:    []string{http.MethodGet},
 			Pattern:    "/hello",
@@ -41,13 +50,12 @@ func ExampleDefaultServer() {
 		log.Printf("%s", err.Error())
 	}
 
-	// Output:
-	// 2023/04/29 21:23:45 add middleware "github.com/razorcorp/go-routing-engine/gre.(*Server).AddCORSHandler.func1"
-	// 2023/04/29 21:23:45 add global handler 404 - not found
-	// 2023/04/29 21:23:45 add global handler 405 - method not allowed
-	// 2023/04/29 21:23:45 add mapping: Hello ( [GET] /hello )
-	// 2023/04/29 21:23:45 starting server daemon
-	// 2023/04/29 21:23:55 stopping server daemon
+	// Log output:
+	// 2023/04/29 21:23:45 INFO add global handler code=404 handler="not found"
+	// 2023/04/29 21:23:45 INFO add global handler code=405 handler="method not allowed"
+	// 2023/04/29 21:23:45 INFO add mapping name=Hello methods=[GET] pattern=/hello
+	// 2023/04/29 21:23:45 INFO starting server daemon addr=0.0.0.0:9999
+	// 2023/04/29 21:23:55 INFO stopping server daemon addr=0.0.0.0:9999
 	// 2023/04/29 21:23:55 http: Server closed
 
 }
@@ -63,20 +71,10 @@ func ExampleNewServer() {
 	//Output:
 }
 
-func appRecovery(next http.Handler) http.Handler {
+// appVersion is an example server middleware adding a response header
+func appVersion(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				_, _ = fmt.Fprintln(os.Stdout, "fetal error, application unexpectedly exited")
-				_, _ = fmt.Fprintf(os.Stdout, "%#v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				response := ErrorResponse{
-					Code:  http.StatusInternalServerError,
-					Cause: "oops, something went wrong. we're looking into it",
-				}
-				fmt.Fprint(w, response.Json())
-			}
-		}()
+		w.Header().Set("X-App-Version", "1.0.0")
 		next.ServeHTTP(w, r)
 	})
 }
@@ -84,16 +82,17 @@ func appRecovery(next http.Handler) http.Handler {
 func ExampleServer_AddMiddleware() {
 	server := DefaultServer(8080, false)
 	server.AddRoutes(Route{
-		Name:       "Crash",
+		Name:       "Hello",
 		Methods:    []string{"GET"},
-		Pattern:    "/crash",
+		Pattern:    "/hello",
 		Deprecated: false,
 		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
-			panic("fake application crash")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, "{\"message\": \"hello\"}")
 		},
 	})
 
-	server.AddMiddleware(appRecovery)
+	server.AddMiddleware(appVersion)
 
 	server.Build()
 
@@ -103,22 +102,18 @@ func ExampleServer_AddMiddleware() {
 		log.Printf("%#v", err)
 	}
 
-	// Output:
-	// 2023/05/01 15:11:29 add middleware "main.appRecovery"
-	// 2023/05/01 15:11:29 add global handler 404 - not found
-	// 2023/05/01 15:11:29 add global handler 405 - method not allowed
-	// 2023/05/01 15:11:29 add mapping: Hello ( [GET] /hello )
-	// 2023/05/01 15:11:29 add mapping: Crash ( [GET] /crash )
-	// 2023/05/01 15:11:29 starting server daemon
-	// 2023/05/01 15:11:30  GET /hello Hello 0s PostmanRuntime/7.32.2
-	// 2023/05/01 15:11:33  GET /crash Crash 0s PostmanRuntime/7.32.2
-	// fetal error, application unexpectedly exited: fake application crash
-	// crash recovered
-}
-
-func ExampleServer_AddResponseMiddleware() {
+	// Log output:
+	// 2023/05/01 15:11:29 INFO add middleware middleware=github.com/razorcorp/go-routing-engine/gre.appVersion
+	// 2023/05/01 15:11:29 INFO add global handler code=404 handler="not found"
+	// 2023/05/01 15:11:29 INFO add global handler code=405 handler="method not allowed"
+	// 2023/05/01 15:11:29 INFO add mapping name=Hello methods=[GET] pattern=/hello
+	// 2023/05/01 15:11:29 INFO starting server daemon addr=0.0.0.0:8080
+	// 2023/05/01 15:11:30 INFO request remote_ip=127.0.0.1 method=GET uri=/hello route=Hello status=200 bytes=20 duration=61.3µs user_agent=PostmanRuntime/7.32.2
+}
+
+func ExampleServer_AddCORSHandler() {
 	server := DefaultServer(8080, false)
-	server.AddMiddleware(appRecovery)
+	server.AddMiddleware(appVersion)
 	server.AddRoutes(Route{Name: "Hello",
 		Methods:    []string{http.MethodGet},
 		Pattern:    "/hello",
@@ -144,12 +139,11 @@ func ExampleServer_AddResponseMiddleware() {
 		log.Printf("%#v", err)
 	}
 
-	// Output:
-	// 2023/05/01 19:37:48 add middleware "github.com/razorcorp/go-routing-engine/gre.appRecovery"
-	// 2023/05/01 19:37:48 add middleware "github.com/razorcorp/go-routing-engine/gre.(*Server).AddCORSHandler.func1"
-	// 2023/05/01 19:37:48 add global handler 404 - not found
-	// 2023/05/01 19:37:48 add global handler 405 - method not allowed
-	// 2023/05/01 19:37:48 add mapping: Hello ( [GET] /hello )
+	// Log output:
+	// 2023/05/01 19:37:48 INFO add middleware middleware=github.com/razorcorp/go-routing-engine/gre.appVersion
+	// 2023/05/01 19:37:48 INFO add global handler code=404 handler="not found"
+	// 2023/05/01 19:37:48 INFO add global handler code=405 handler="method not allowed"
+	// 2023/05/01 19:37:48 INFO add mapping name=Hello methods=[GET] pattern=/hello
 }
 
 func ExampleServer_AddRoutes() {
@@ -174,10 +168,470 @@ func ExampleServer_AddRoutes() {
 		log.Printf("%s", err.Error())
 	}
 
+	// Log output:
+	// 2023/04/29 21:23:45 INFO add middleware middleware=github.com/razorcorp/go-routing-engine/gre.(*Server).AddCORSHandler.func1
+	// 2023/04/29 21:23:45 INFO add global handler code=404 handler="not found"
+	// 2023/04/29 21:23:45 INFO add global handler code=405 handler="method not allowed"
+	// 2023/04/29 21:23:45 INFO add mapping name=Hello methods=[GET] pattern=/hello
+
+}
+
+func ExampleServer_UseRouteTable() {
+	defer func(table Routes) { RouteTable = table }(RouteTable)
+
+	RouteTable = append(RouteTable, Route{Name: "Shared",
+		Methods:     []string{http.MethodGet},
+		Pattern:     "/shared",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+	})
+
+	public := NewServer().
+		AddRoutes(Route{Name: "Hello",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/hello",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		})
+
+	admin := NewServer().
+		UseRouteTable().
+		AddRoutes(Route{Name: "Users",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/users",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		})
+
+	for _, route := range public.Routes() {
+		fmt.Println("public:", route.Name, route.Pattern)
+	}
+	for _, route := range admin.Routes() {
+		fmt.Println("admin:", route.Name, route.Pattern)
+	}
+
+	// Output:
+	// public: Health /health
+	// public: Hello /hello
+	// admin: Health /health
+	// admin: Shared /shared
+	// admin: Users /users
+}
+
+func ExampleServer_AddGroups() {
+	requireToken := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server := NewServer().
+		AddRoutes(Route{Name: "Hello",
+			Methods: []string{http.MethodGet},
+			Pattern: "/hello",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+		}).
+		AddGroups(RouteGroup{
+			Prefix:      "/api/v1",
+			Middlewares: []func(http.Handler) http.Handler{requireToken},
+			Routes: Routes{
+				Route{Name: "Users",
+					Methods: []string{http.MethodGet},
+					Pattern: "/users",
+					HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusOK)
+					},
+				},
+			},
+		}).
+		Build()
+
+	for _, path := range []string{"/hello", "/api/v1/users"} {
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Println(path, w.Code)
+	}
+
+	// Output:
+	// /hello 200
+	// /api/v1/users 401
+}
+
+func ExampleOpenAPIInfo() {
+	hello := func(w http.ResponseWriter, r *http.Request) {}
+	server := NewServer().
+		AddOpenAPI(OpenAPIInfo{}).
+		AddRoutes(Route{Name: "Hello", Methods: []string{http.MethodGet}, Pattern: "/hello", HandlerFunc: hello}).
+		Build()
+
+	// the document describes the routes served since Build
+	server.AddRoutes(Route{Name: "Bye", Methods: []string{http.MethodGet}, Pattern: "/bye", HandlerFunc: hello})
+
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var doc struct {
+		Info struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]any `json:"paths"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &doc)
+	_, bye := doc.Paths["/bye"]
+	fmt.Println(doc.Info.Title, doc.Info.Version, len(doc.Paths), bye)
+
+	// Output:
+	// API 0.0.0 3 false
+}
+
+func ExampleServer_AddOpenAPI() {
+	server := NewServer().
+		AddOpenAPI(OpenAPIInfo{Path: "/docs/openapi.json", Title: "Users", Version: "1.0.0"}).
+		AddRoutes(
+			Route{Name: "GetUser",
+				Methods:     []string{http.MethodGet},
+				Pattern:     "/user/{name}",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+				Spec: &OperationSpec{
+					Summary: "Get a user by name",
+					Responses: map[int]ResponseSpec{
+						http.StatusOK: {Schema: Schema{"type": "object"}},
+					},
+				},
+			},
+			Route{Name: "DeleteUser",
+				Methods:     []string{http.MethodDelete},
+				Pattern:     "/user/{name}",
+				Deprecated:  true,
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+			},
+			Route{Name: "UpdateUser",
+				Methods:     []string{http.MethodPut, http.MethodPatch},
+				Pattern:     "/user/{name}",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+			},
+		).
+		Build()
+
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/openapi.json", nil))
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Summary     string `json:"summary"`
+			Deprecated  bool   `json:"deprecated"`
+			Parameters  []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &doc)
+
+	fmt.Println(doc.OpenAPI)
+	for _, method := range []string{"get", "delete", "put", "patch"} {
+		op := doc.Paths["/user/{name}"][method]
+		fmt.Println(method, op.OperationID, op.Summary, op.Deprecated, op.Parameters[0].Name, op.Parameters[0].In)
+	}
+
+	// Output:
+	// 3.1.0
+	// get GetUser Get a user by name false name path
+	// delete DeleteUser DeleteUser true name path
+	// put UpdateUser_put UpdateUser false name path
+	// patch UpdateUser_patch UpdateUser false name path
+}
+
+func ExampleServer_UseLogger() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	NewServer().
+		UseLogger(logger).
+		AddRoutes(Route{Name: "Hello",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/hello",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		}).
+		Build()
+
+	// Output:
+	// level=INFO msg="add global handler" code=404 handler="not found"
+	// level=INFO msg="add global handler" code=405 handler="method not allowed"
+	// level=INFO msg="add mapping" name="Prometheus metrics" methods=[GET] pattern=/metrics
+	// level=INFO msg="add mapping" name=Health methods=[GET] pattern=/health
+	// level=INFO msg="add mapping" name=Hello methods=[GET] pattern=/hello
+}
+
+func ExampleServer_Listen() {
+	occupied, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer occupied.Close()
+
+	server := NewServer()
+	server.Addr = occupied.Addr().String()
+
+	errs, err := server.Listen()
+	if err != nil {
+		fmt.Println("bind failed:", errors.Is(err, syscall.EADDRINUSE))
+		return
+	}
+
+	if err := <-errs; err != nil {
+		log.Printf("%s", err.Error())
+	}
+
 	// Output:
-	// 2023/04/29 21:23:45 add middleware "github.com/razorcorp/go-routing-engine/gre.(*Server).AddCORSHandler.func1"
-	// 2023/04/29 21:23:45 add global handler 404 - not found
-	// 2023/04/29 21:23:45 add global handler 405 - method not allowed
-	// 2023/04/29 21:23:45 add mapping: Hello ( [GET] /hello )
+	// bind failed: true
+}
+
+func ExampleServer_Build() {
+	hello := func(w http.ResponseWriter, r *http.Request) {}
+
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		AddRoutes(
+			Route{Name: "Hello", Methods: []string{http.MethodGet}, Pattern: "/hello", HandlerFunc: hello},
+			Route{Name: "Hello", Methods: []string{http.MethodGet}, Pattern: "/hi", HandlerFunc: hello},
+		)
+	server.Addr = "127.0.0.1:0"
+
+	// route conflicts found by Build are returned by Listen and Run
+	_, err := server.Listen()
+	fmt.Println(err)
+
+	// Output:
+	// route conflict: name "Hello" is already registered
+}
+
+func ExampleServer_Build_listen() {
+	var logs bytes.Buffer
+	hello := func(w http.ResponseWriter, r *http.Request) {}
+
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		AddRoutes(Route{Name: "Hello", Methods: []string{http.MethodGet}, Pattern: "/hello", HandlerFunc: hello}).
+		Build()
+	server.Addr = "127.0.0.1:0"
+
+	// Listen serves the handler built before, unless the routes changed
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer server.Stop()
+	fmt.Println("mappings:", strings.Count(logs.String(), `msg="add mapping"`))
+
+	// Output:
+	// mappings: 3
+}
+
+func ExampleServer_Run() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	server := NewServer()
+	server.Addr = "127.0.0.1:0"
+
+	// Run blocks until the context is done, typically created
+	// with signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	err := server.Run(ctx)
+	fmt.Println("stopped:", err)
+
+	// Output:
+	// stopped: <nil>
+}
+
+func ExampleServer_Draining() {
+	server := NewServer()
+	server.Addr = "127.0.0.1:0"
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	_ = server.Stop()
+	fmt.Println("stopped:", server.Draining())
+
+	errs, err := server.Listen()
+	if err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	fmt.Println("listening again:", server.Draining())
+
+	// the embedded http.Server can't serve once it has been shut down
+	fmt.Println(<-errs)
+	_ = server.Stop()
+
+	// Output:
+	// stopped: true
+	// listening again: false
+	// http: Server closed
+}
+
+func ExampleServer_UseShutdown() {
+	reserved, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := reserved.Addr().String()
+	_ = reserved.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	server := NewServer().
+		UseShutdown(ShutdownConfig{
+			PreShutdownDelay: 10 * time.Millisecond,
+			DrainTimeout:     50 * time.Millisecond,
+		}).
+		AddRoutes(Route{Name: "Slow",
+			Methods: []string{http.MethodGet},
+			Pattern: "/slow",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			},
+		})
+	server.Addr = addr
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+
+	go http.Get("http://" + addr + "/slow")
+	for server.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := server.Stop()
+
+	var drain *DrainTimeoutError
+	fmt.Println("in flight:", errors.As(err, &drain), drain.InFlight)
+
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	fmt.Println("health:", w.Code, w.Body.String())
+
+	// Output:
+	// in flight: true 1
+	// health: 503 {"code":503,"status":"Draining"}
+}
+
+func ExampleServer_OnStart() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	hook := func(message string) Hook {
+		return func(ctx context.Context) error {
+			fmt.Println(message)
+			return nil
+		}
+	}
+
+	server := NewServer().
+		OnStart(hook("open database pool")).
+		OnStart(hook("open cache client")).
+		OnReady(hook("register with discovery")).
+		OnShutdown(hook("deregister from discovery")).
+		OnStopped(hook("close database pool")).
+		OnStopped(hook("close cache client"))
+	server.Addr = "127.0.0.1:0"
+
+	if err := server.Run(ctx); err != nil {
+		log.Printf("%s", err.Error())
+	}
+
+	// Output:
+	// open database pool
+	// open cache client
+	// register with discovery
+	// deregister from discovery
+	// close cache client
+	// close database pool
+}
 
+func ExampleServer_OnReady() {
+	errUnavailable := errors.New("discovery unavailable")
+	hook := func(message string, err error) Hook {
+		return func(ctx context.Context) error {
+			fmt.Println(message)
+			return err
+		}
+	}
+
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		OnStart(hook("open database pool", nil)).
+		OnReady(hook("register with discovery", errUnavailable)).
+		OnStopped(hook("close database pool", nil))
+	server.Addr = "127.0.0.1:0"
+
+	// the OnStopped hooks undo the OnStart hooks of a server failing to start
+	_, err := server.Listen()
+	fmt.Println(errors.Is(err, errUnavailable))
+
+	// Output:
+	// open database pool
+	// register with discovery
+	// close database pool
+	// true
+}
+
+func ExampleServer_Stop() {
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		OnStopped(func(ctx context.Context) error {
+			fmt.Println("close database pool")
+			return nil
+		})
+	server.Addr = "127.0.0.1:0"
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+
+	// the hooks only run the first time the server is stopped
+	fmt.Println(server.Stop())
+	fmt.Println(server.Stop())
+
+	// Output:
+	// close database pool
+	// <nil>
+	// <nil>
+}
+
+func ExampleServer_Start_listenError() {
+	occupied, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer occupied.Close()
+
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		OnShutdown(func(ctx context.Context) error {
+			fmt.Println("deregister from discovery")
+			return nil
+		}).
+		OnStopped(func(ctx context.Context) error {
+			fmt.Println("close database pool")
+			return nil
+		})
+	server.Addr = occupied.Addr().String()
+
+	// the hooks are skipped as the server never started
+	fmt.Println(<-server.Start())
+	fmt.Println(server.Stop())
+
+	// Output:
+	// terminated
+	// <nil>
 }
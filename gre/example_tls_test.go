@@ -0,0 +1,282 @@
+package gre
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeCertificate writes a certificate and key for 127.0.0.1 signed
+// by the parent, or self-signed when parent is nil
+func writeCertificate(dir, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, _ := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	_ = os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func serverCertificate(commonName string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+func ExampleServer_UseTLS() {
+	dir, _ := os.MkdirTemp("", "gre-tls")
+	defer os.RemoveAll(dir)
+
+	writeCertificate(dir, "server", serverCertificate("first"), nil, nil)
+
+	reserved, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := reserved.Addr().String()
+	_ = reserved.Close()
+
+	server := NewServer().
+		UseTLS(TLSConfig{
+			CertFile:       filepath.Join(dir, "server.crt"),
+			KeyFile:        filepath.Join(dir, "server.key"),
+			MinVersion:     tls.VersionTLS13,
+			ReloadInterval: time.Minute,
+		})
+	server.Addr = addr
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer server.Stop()
+
+	// the example trusts any certificate and prints the served common name
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	servedName := func() string {
+		resp, err := client.Get("https://" + addr + "/health")
+		if err != nil {
+			return err.Error()
+		}
+		defer resp.Body.Close()
+		client.CloseIdleConnections()
+		return fmt.Sprintf("%s %s", resp.TLS.PeerCertificates[0].Subject.CommonName, resp.Proto)
+	}
+
+	fmt.Println(servedName())
+
+	// rotate the certificate on disk, reloading it without
+	// waiting for the ReloadInterval
+	writeCertificate(dir, "server", serverCertificate("second"), nil, nil)
+	if err := server.ReloadTLS(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+
+	fmt.Println(servedName())
+
+	// Output:
+	// first HTTP/2.0
+	// second HTTP/2.0
+}
+
+func ExampleTLSConfig_base() {
+	dir, _ := os.MkdirTemp("", "gre-tls")
+	defer os.RemoveAll(dir)
+
+	writeCertificate(dir, "server", serverCertificate("server"), nil, nil)
+
+	reserved, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := reserved.Addr().String()
+	_ = reserved.Close()
+
+	server := NewServer().
+		UseTLS(TLSConfig{
+			CertFile: filepath.Join(dir, "server.crt"),
+			KeyFile:  filepath.Join(dir, "server.key"),
+		})
+	server.Addr = addr
+	// the settings of the http.Server TLSConfig are kept
+	server.TLSConfig = &tls.Config{
+		MaxVersion: tls.VersionTLS12,
+		NextProtos: []string{"http/1.1"},
+	}
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer server.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + addr + "/health")
+	if err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	fmt.Println(resp.TLS.PeerCertificates[0].Subject.CommonName, resp.Proto, tls.VersionName(resp.TLS.Version))
+
+	// Output:
+	// server HTTP/1.1 TLS 1.2
+}
+
+func ExampleClientIdentityFromContext() {
+	dir, _ := os.MkdirTemp("", "gre-mtls")
+	defer os.RemoveAll(dir)
+
+	ca, caKey := writeCertificate(dir, "ca", &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "example CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	writeCertificate(dir, "server", serverCertificate("server"), ca, caKey)
+
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/billing")
+	writeCertificate(dir, "client", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "billing"},
+		URIs:        []*url.URL{spiffeID},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	reserved, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := reserved.Addr().String()
+	_ = reserved.Close()
+
+	server := NewServer().
+		UseTLS(TLSConfig{
+			CertFile:     filepath.Join(dir, "server.crt"),
+			KeyFile:      filepath.Join(dir, "server.key"),
+			ClientCAFile: filepath.Join(dir, "ca.crt"),
+		}).
+		AddRoutes(
+			Route{Name: "Invoices",
+				Methods:        []string{http.MethodGet},
+				Pattern:        "/invoices",
+				AllowedClients: []string{"spiffe://example.org/ns/prod/sa/billing"},
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					identity, _ := ClientIdentityFromContext(r.Context())
+					fmt.Fprint(w, identity.SPIFFEID)
+				},
+			},
+			Route{Name: "Admin",
+				Methods:        []string{http.MethodGet},
+				Pattern:        "/admin",
+				AllowedClients: []string{"spiffe://example.org/ns/ops/*"},
+				HandlerFunc:    func(w http.ResponseWriter, r *http.Request) {},
+			},
+		)
+	server.Addr = addr
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer server.Stop()
+
+	cert, _ := tls.LoadX509KeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+	}}
+
+	for _, path := range []string{"/invoices", "/admin"} {
+		req, _ := http.NewRequest(http.MethodGet, "https://"+addr+path, nil)
+		// the request ID of the client is returned in the error responses
+		req.Header.Set("X-Request-ID", "billing-run-42")
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Printf("%s", err.Error())
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		fmt.Println(path, resp.StatusCode, string(body))
+	}
+
+	// Output:
+	// /invoices 200 spiffe://example.org/ns/prod/sa/billing
+	// /admin 403 {"code":403,"cause":"client not allowed","request_id":"billing-run-42"}
+}
+
+func ExampleRoute_allowedClients() {
+	server := NewServer().
+		AddRoutes(Route{Name: "Invoices",
+			Methods: []string{http.MethodGet},
+			Pattern: "/invoices",
+			AllowedClients: []string{
+				"spiffe://example.org/ns/prod/sa/billing",
+				"*.billing.example.org",
+				"CN=billing-batch",
+			},
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {},
+		}).
+		Build()
+
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/billing")
+	for _, client := range []struct {
+		name string
+		cert *x509.Certificate
+	}{
+		{"spiffe id", &x509.Certificate{URIs: []*url.URL{spiffeID}}},
+		{"dns wildcard", &x509.Certificate{DNSNames: []string{"eu.billing.example.org"}}},
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: "billing-batch"}}},
+		// identities of another type don't match the patterns
+		{"spoofed cn", &x509.Certificate{Subject: pkix.Name{CommonName: "spiffe://example.org/ns/prod/sa/billing"}}},
+		{"spoofed dns", &x509.Certificate{DNSNames: []string{"spiffe://example.org/ns/prod/sa/billing"}}},
+		{"cn as dns name", &x509.Certificate{Subject: pkix.Name{CommonName: "eu.billing.example.org"}}},
+		{"nested dns", &x509.Certificate{DNSNames: []string{"a.eu.billing.example.org"}}},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{client.cert}}}
+
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, r)
+		fmt.Println(client.name, w.Code)
+	}
+
+	// Output:
+	// spiffe id 200
+	// dns wildcard 200
+	// common name 200
+	// spoofed cn 403
+	// spoofed dns 403
+	// cn as dns name 403
+	// nested dns 403
+}
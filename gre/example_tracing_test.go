@@ -0,0 +1,98 @@
+package gre
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+)
+
+func ExampleServer_UseTracing() {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	// the parent trace context is extracted by the global propagator
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	// the example prints the trace of the panic log
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey, "request_id", "span_id", "stack":
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	registry := prometheus.NewRegistry()
+	server := NewServer().
+		UseLogger(logger).
+		UseMetrics(MetricsConfig{Registerer: registry}).
+		UseAccessLog(TemplateLogFormat("{status} {route} {trace_id}"), os.Stdout).
+		UseTracing(TracingConfig{TracerProvider: provider}).
+		AddRoutes(
+			Route{Name: "GetUser", Methods: []string{http.MethodGet}, Pattern: "/user/{name}",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {}},
+			Route{Methods: []string{http.MethodGet}, Pattern: "/orders/{id}",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {}},
+			Route{Name: "Crash", Methods: []string{http.MethodGet}, Pattern: "/crash",
+				HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+					panic("fake application crash")
+				}},
+		).
+		Build()
+
+	for _, path := range []string{"/user/frank", "/orders/42", "/crash", "/missing"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		// the span joins the trace of the caller
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		server.Handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	for _, span := range exporter.GetSpans() {
+		var status attribute.Value
+		for _, attr := range span.Attributes {
+			if attr.Key == "http.response.status_code" {
+				status = attr.Value
+			}
+		}
+		fmt.Printf("%s: kind=%s parent=%s status=%d %s events=%d\n", span.Name, span.SpanKind,
+			span.Parent.SpanID(), status.AsInt64(), span.Status.Code, len(span.Events))
+	}
+
+	families, _ := registry.Gather()
+	for _, family := range families {
+		if family.GetName() == "http_requests_total" {
+			for _, metric := range family.GetMetric() {
+				fmt.Println(family.GetName(), metric.GetLabel()[3].GetValue(),
+					metric.GetCounter().GetExemplar().GetLabel()[0].GetValue())
+			}
+		}
+	}
+
+	// Output:
+	// 200 GetUser 4bf92f3577b34da6a3ce929d0e0e4736
+	// 200  4bf92f3577b34da6a3ce929d0e0e4736
+	// level=ERROR msg="panic recovered" route=Crash trace_id=4bf92f3577b34da6a3ce929d0e0e4736 panic="fake application crash"
+	// 500 Crash 4bf92f3577b34da6a3ce929d0e0e4736
+	// GetUser: kind=server parent=00f067aa0ba902b7 status=200 Unset events=0
+	// GET /orders/{id}: kind=server parent=00f067aa0ba902b7 status=200 Unset events=0
+	// Crash: kind=server parent=00f067aa0ba902b7 status=500 Error events=1
+	// GET: kind=server parent=00f067aa0ba902b7 status=404 Unset events=0
+	// http_requests_total /orders/{id} 4bf92f3577b34da6a3ce929d0e0e4736
+	// http_requests_total /user/{name} 4bf92f3577b34da6a3ce929d0e0e4736
+	// http_requests_total unmatched 4bf92f3577b34da6a3ce929d0e0e4736
+	// http_requests_total /crash 4bf92f3577b34da6a3ce929d0e0e4736
+}
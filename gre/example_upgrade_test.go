@@ -0,0 +1,122 @@
+//go:build unix
+
+package gre
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const upgradeExampleEnv = "GRE_EXAMPLE_UPGRADE"
+
+// pidRoute responds with the id of the process serving the request
+var pidRoute = Route{
+	Name:    "Pid",
+	Methods: []string{http.MethodGet},
+	Pattern: "/pid",
+	HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, os.Getpid())
+	},
+}
+
+// TestMain runs the examples, the test binary started by
+// ExampleServer_Upgrade serves the inherited listeners instead
+// until it is terminated
+func TestMain(m *testing.M) {
+	if os.Getenv(upgradeExampleEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	server := NewServer().
+		UseLogger(slog.New(slog.NewTextHandler(io.Discard, nil))).
+		AddRoutes(pidRoute)
+	server.Addr = "127.0.0.1:0"
+	if err := server.Run(ctx); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func ExampleServer_Upgrade() {
+	server := NewServer().
+		UseUpgrade(UpgradeConfig{ReadyTimeout: 10 * time.Second}).
+		AddRoutes(pidRoute)
+	server.Addr = "127.0.0.1:0"
+
+	if _, err := server.Listen(); err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	addr := server.Addrs()[0].String()
+
+	_ = os.Setenv(upgradeExampleEnv, "1")
+	err := server.Upgrade()
+	_ = os.Unsetenv(upgradeExampleEnv)
+	if err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+
+	// the new process keeps serving the listener once this server is stopped
+	if err := server.Stop(); err != nil {
+		log.Printf("%s", err.Error())
+	}
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr + "/pid")
+	if err != nil {
+		log.Printf("%s", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	pid, _ := strconv.Atoi(string(body))
+	fmt.Println("served by new process:", pid != os.Getpid())
+
+	_ = syscall.Kill(pid, syscall.SIGTERM)
+
+	// Output:
+	// served by new process: true
+}
+
+func Example_upgradeListenerMismatch() {
+	inherited, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer inherited.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			if a.Key == "addr" && a.Value.String() == inherited.Addr().String() {
+				return slog.String("addr", "inherited")
+			}
+			return a
+		},
+	}))
+
+	// the new executable listens on a Unix socket instead
+	server := NewServer().
+		UseLogger(logger).
+		AddListener(ListenerConfig{Network: "unix", Address: "/run/gre/api.sock"})
+	server.checkInherited(&handoff{listeners: []net.Listener{inherited}}, ":http")
+
+	// Output:
+	// level=WARN msg="configured listener not inherited" network=unix addr=/run/gre/api.sock
+	// level=WARN msg="inherited listener not configured" network=tcp addr=inherited
+}
@@ -0,0 +1,200 @@
+package gre
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// LivenessProbe is served on /livez by default and fails when
+	// a critical liveness check fails
+	LivenessProbe Probe = "livez"
+
+	// ReadinessProbe is served on /readyz by default and fails when a
+	// critical readiness check fails or the server is draining
+	ReadinessProbe Probe = "readyz"
+
+	// StartupProbe is served on /startupz by default and fails until
+	// the server is listening and the critical startup checks pass
+	StartupProbe Probe = "startupz"
+
+	defaultCheckTimeout = time.Second
+)
+
+// UseProbes serves the liveness, readiness and startup probes
+//
+// param: <probes> is ProbesConfig definition of the probe paths
+func (s *Server) UseProbes(probes ProbesConfig) *Server {
+	s.probes = &probes
+	return s
+}
+
+// AddHealthCheck adds a HealthCheck to the health probes enabled with UseProbes
+//
+// param: <check> is HealthCheck definition of the check
+func (s *Server) AddHealthCheck(check HealthCheck) *Server {
+	s.healthChecks = append(s.healthChecks, check)
+	return s
+}
+
+// path returns the configured path of the probe
+func (c ProbesConfig) path(probe Probe) string {
+	path := map[Probe]string{
+		LivenessProbe:  c.LivenessPath,
+		ReadinessProbe: c.ReadinessPath,
+		StartupProbe:   c.StartupPath,
+	}[probe]
+	if path == "" {
+		return "/" + string(probe)
+	}
+	return path
+}
+
+// healthRoute is the built-in /health route, failing once
+// the server is draining
+func (s *Server) healthRoute() Route {
+	return Route{
+		Name:        "Health",
+		Methods:     []string{http.MethodGet},
+		Pattern:     "/health",
+		ContentType: "application/json",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			if s.Draining() {
+				writeResponse(w, &response{Code: http.StatusServiceUnavailable, Status: "Draining"})
+				return
+			}
+			writeResponse(w, &response{Code: http.StatusOK, Status: "Alive"})
+		},
+	}
+}
+
+// probeRoute returns the route serving the probe on the path set
+// by UseProbes, the per check
+// error and duration are only included with the verbose query parameter
+// example: /readyz?verbose
+func (s *Server) probeRoute(probe Probe) Route {
+	names := map[Probe][3]string{
+		LivenessProbe:  {"Liveness", "Live", "Not live"},
+		ReadinessProbe: {"Readiness", "Ready", "Not ready"},
+		StartupProbe:   {"Startup", "Started", "Starting"},
+	}[probe]
+
+	return Route{
+		Name:        names[0],
+		Methods:     []string{http.MethodGet},
+		Pattern:     s.probes.path(probe),
+		ContentType: "application/json",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			_, verbose := r.URL.Query()["verbose"]
+			checks, healthy := s.runHealthChecks(r.Context(), probe, verbose)
+
+			resp := &response{Code: http.StatusOK, Status: names[1], Checks: checks}
+			switch {
+			case probe == ReadinessProbe && s.Draining():
+				resp.Status = "Draining"
+				healthy = false
+			case probe == StartupProbe && !s.started.Load():
+				healthy = false
+			}
+			if !healthy {
+				resp.Code = http.StatusServiceUnavailable
+				if resp.Status != "Draining" {
+					resp.Status = names[2]
+				}
+			}
+
+			writeResponse(w, resp)
+		},
+	}
+}
+
+// runHealthChecks runs the checks of the probe concurrently and
+// reports whether all the critical checks passed
+func (s *Server) runHealthChecks(ctx context.Context, probe Probe, verbose bool) ([]checkResult, bool) {
+	var checks []HealthCheck
+	for _, check := range s.healthChecks {
+		probes := check.Probes
+		if len(probes) == 0 {
+			probes = []Probe{ReadinessProbe}
+		}
+		for _, p := range probes {
+			if p == probe {
+				checks = append(checks, check)
+				break
+			}
+		}
+	}
+
+	results := make([]checkResult, len(checks))
+	healthy := true
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := runHealthCheck(ctx, check)
+			result := checkResult{Name: check.Name, Status: "pass", Critical: check.Critical}
+			if err != nil {
+				result.Status = "fail"
+			}
+			if verbose {
+				result.Duration = time.Since(start).String()
+				if err != nil {
+					result.Error = err.Error()
+				}
+			}
+			results[i] = result
+
+			if err != nil && check.Critical {
+				mu.Lock()
+				healthy = false
+				mu.Unlock()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	return results, healthy
+}
+
+// runHealthCheck runs the check within its timeout, a check that
+// ignores the context fails once the timeout is exceeded and a
+// check that panics fails with the panic value
+func runHealthCheck(ctx context.Context, check HealthCheck) error {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				result <- fmt.Errorf("check panicked: %v", value)
+			}
+		}()
+		result <- check.Check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %s: %w", timeout, ctx.Err())
+	}
+}
+
+func writeResponse(w http.ResponseWriter, resp *response) {
+	w.WriteHeader(resp.Code)
+	fmt.Fprint(w, resp.json())
+}
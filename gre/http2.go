@@ -0,0 +1,42 @@
+package gre
+
+import (
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// UseHTTP2 configures the HTTP/2 settings of the server and
+// enables HTTP/2 over cleartext when HTTP2Config.H2C is set.
+// HTTPS servers negotiate HTTP/2 without this configuration
+//
+// param: <config> is HTTP2Config definition of the HTTP/2 server
+func (s *Server) UseHTTP2(config HTTP2Config) *Server {
+	s.http2 = &config
+	return s
+}
+
+// configureHTTP2 registers the HTTP/2 server on the http.Server once so
+// the connections are closed gracefully on Shutdown, and wraps the
+// handler built by Build with h2c when cleartext HTTP/2 is enabled
+func (s *Server) configureHTTP2() error {
+	if s.http2 == nil {
+		return nil
+	}
+
+	if s.http2Server == nil {
+		h2s := &http2.Server{
+			MaxConcurrentStreams: s.http2.MaxConcurrentStreams,
+			MaxReadFrameSize:     s.http2.MaxReadFrameSize,
+			IdleTimeout:          s.http2.IdleTimeout,
+		}
+		if err := http2.ConfigureServer(&s.Server, h2s); err != nil {
+			return err
+		}
+		s.http2Server = h2s
+	}
+
+	if s.http2.H2C {
+		s.Handler = h2c.NewHandler(s.Handler, s.http2Server)
+	}
+	return nil
+}
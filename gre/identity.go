@@ -0,0 +1,133 @@
+package gre
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type clientIdentityKey struct{}
+
+// ClientIdentityFromContext returns the verified mutual TLS client
+// identity of the request
+//
+// param: <ctx> is the request context
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return identity, ok
+}
+
+// clientIdentity adds the ClientIdentity of a verified client
+// certificate to the request context
+func clientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		identity := &ClientIdentity{
+			Subject:        cert.Subject.String(),
+			CommonName:     cert.Subject.CommonName,
+			DNSNames:       cert.DNSNames,
+			EmailAddresses: cert.EmailAddresses,
+			Certificate:    cert,
+		}
+		for _, uri := range cert.URIs {
+			identity.URIs = append(identity.URIs, uri.String())
+			if uri.Scheme == "spiffe" && identity.SPIFFEID == "" {
+				identity.SPIFFEID = uri.String()
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIdentityKey{}, identity)))
+	})
+}
+
+// allowClients only serves requests from a client identity matching
+// one of the allowed identities
+func (c routerConfig) allowClients(allowed []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := ClientIdentityFromContext(r.Context())
+		if !ok {
+			c.writeError(w, r, &ErrorResponse{
+				Code:  http.StatusUnauthorized,
+				Cause: "client certificate required",
+			})
+			return
+		}
+
+		if !identity.matches(allowed) {
+			c.writeError(w, r, &ErrorResponse{
+				Code:  http.StatusForbidden,
+				Cause: "client not allowed",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matches reports whether one of the allowed patterns matches the
+// identity names of the same type, see Route.AllowedClients
+func (i *ClientIdentity) matches(allowed []string) bool {
+	for _, pattern := range allowed {
+		var (
+			names []string
+			match func(pattern, name string) bool
+		)
+		switch {
+		case strings.Contains(pattern, "://"):
+			names, match = i.URIs, matchURI
+		case strings.HasPrefix(pattern, "CN="):
+			pattern = strings.TrimPrefix(pattern, "CN=")
+			names, match = []string{i.CommonName}, matchExact
+		case strings.Contains(pattern, "@"):
+			names, match = i.EmailAddresses, matchEmail
+		default:
+			names, match = i.DNSNames, matchDNS
+		}
+
+		for _, name := range names {
+			if name != "" && match(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchExact(pattern, name string) bool {
+	return pattern == name
+}
+
+// matchURI matches the URI, a trailing "*" matches any suffix
+// example: "spiffe://example.org/ns/ops/*"
+func matchURI(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+// matchDNS matches the DNS name, a leading "*." matches a single label
+// example: "*.billing.example.org"
+func matchDNS(pattern, name string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		label, rest, found := strings.Cut(name, ".")
+		return found && label != "" && strings.EqualFold(rest, suffix)
+	}
+	return strings.EqualFold(pattern, name)
+}
+
+// matchEmail matches the email address, "*@" matches any mailbox of the domain
+// example: "*@example.org"
+func matchEmail(pattern, name string) bool {
+	if domain, ok := strings.CutPrefix(pattern, "*@"); ok {
+		mailbox, rest, found := strings.Cut(name, "@")
+		return found && mailbox != "" && strings.EqualFold(rest, domain)
+	}
+	return pattern == name
+}
@@ -0,0 +1,94 @@
+package gre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// OnStart registers a Hook invoked once the listeners are bound and before
+// the server serves requests, an error prevents the server from starting
+// and invokes the OnStopped hooks
+//
+// param: <hook> is the Hook to invoke
+func (s *Server) OnStart(hook Hook) *Server {
+	s.hooks.start = append(s.hooks.start, hook)
+	return s
+}
+
+// OnReady registers a Hook invoked once the listeners are bound and
+// the server is serving requests, an error stops the server and
+// invokes the OnStopped hooks
+//
+// param: <hook> is the Hook to invoke
+func (s *Server) OnReady(hook Hook) *Server {
+	s.hooks.ready = append(s.hooks.ready, hook)
+	return s
+}
+
+// OnShutdown registers a Hook invoked once when the shutdown begins,
+// either when a signal arrives on the channel returned by Start, when
+// the Run context is done, or when Stop is invoked
+//
+// param: <hook> is the Hook to invoke
+func (s *Server) OnShutdown(hook Hook) *Server {
+	s.hooks.shutdown = append(s.hooks.shutdown, hook)
+	return s
+}
+
+// OnStopped registers a Hook invoked after Stop has shut down the server
+//
+// param: <hook> is the Hook to invoke
+func (s *Server) OnStopped(hook Hook) *Server {
+	s.hooks.stopped = append(s.hooks.stopped, hook)
+	return s
+}
+
+// beginShutdown invokes the OnShutdown hooks the first time it is called
+func (s *Server) beginShutdown() error {
+	s.shutdownOnce.Do(func() {
+		ctx, cancel := s.hookContext()
+		defer cancel()
+		s.shutdownErr = s.runHooks(ctx, "shutdown", s.hooks.shutdown, true)
+	})
+	return s.shutdownErr
+}
+
+// hookContext returns the context for the shutdown and stopped
+// hooks, bounded by the drain timeout
+func (s *Server) hookContext() (context.Context, context.CancelFunc) {
+	timeout := s.shutdown.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// runHooks invokes the hooks in registration order, or in reverse
+// order when reverse is set. Start and ready hooks stop at the first
+// error, while all shutdown and stopped hooks are invoked
+func (s *Server) runHooks(ctx context.Context, phase string, hooks []Hook, reverse bool) error {
+	logger := s.config.log()
+
+	var errs []error
+	for i := range hooks {
+		hook := hooks[i]
+		if reverse {
+			hook = hooks[len(hooks)-1-i]
+		}
+
+		name := runtime.FuncForPC(reflect.ValueOf(hook).Pointer()).Name()
+		logger.Info("run lifecycle hook", "phase", phase, "hook", name)
+		if err := hook(ctx); err != nil {
+			logger.Error("lifecycle hook failed", "phase", phase, "hook", name, "error", err)
+			errs = append(errs, fmt.Errorf("%s hook %s: %w", phase, name, err))
+			if !reverse {
+				break
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,276 @@
+package gre
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd
+const listenFdsStart = 3
+
+// AddListener adds an address the server listens on in addition to
+// Server.Addr, the Addr is only bound by default when no other
+// listener is configured
+//
+// param: <config> is ListenerConfig definition of the listener
+func (s *Server) AddListener(config ListenerConfig) *Server {
+	s.listeners = append(s.listeners, config)
+	return s
+}
+
+// UseSystemdListeners serves on the sockets passed by systemd socket
+// activation using the LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES
+// environment variables, which are unset once the sockets are inherited.
+// When systemd passed any socket, the Addr and the listeners added with
+// AddListener are not bound
+func (s *Server) UseSystemdListeners() *Server {
+	s.systemd = true
+	return s
+}
+
+// Addrs returns the addresses the server is listening on
+func (s *Server) Addrs() []net.Addr {
+	addrs := make([]net.Addr, 0, len(s.bound))
+	for _, l := range s.bound {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
+
+// listen returns the sockets passed by systemd or binds the configured
+// listeners, closing the ones already bound when any of them fails
+func (s *Server) listen(defaultAddr string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	fail := func(err error) ([]net.Listener, error) {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+		return nil, err
+	}
+
+	if s.systemd {
+		inherited, err := systemdListeners()
+		if err != nil {
+			return nil, err
+		}
+		if len(inherited) > 0 {
+			return inherited, nil
+		}
+	}
+
+	for _, config := range s.listenerConfigs(defaultAddr) {
+		l, err := config.listen()
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// listenerConfigs returns the listeners to bind, the Addr is bound
+// when set or when there are no other listeners
+func (s *Server) listenerConfigs(defaultAddr string) []ListenerConfig {
+	configs := s.listeners
+	if s.Addr != "" || len(configs) == 0 {
+		addr := s.Addr
+		if addr == "" {
+			addr = defaultAddr
+		}
+		configs = append([]ListenerConfig{{Network: "tcp", Address: addr}}, configs...)
+	}
+	return configs
+}
+
+func (c ListenerConfig) network() string {
+	if c.Network == "" {
+		return "tcp"
+	}
+	return c.Network
+}
+
+// matches reports whether the listener address is the configured one,
+// unspecified TCP ports and host names match any port and address
+func (c ListenerConfig) matches(addr net.Addr) bool {
+	network := c.network()
+	if strings.HasPrefix(network, "unix") {
+		return strings.HasPrefix(addr.Network(), "unix") && addr.String() == c.Address
+	}
+
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	host, port, err := net.SplitHostPort(c.Address)
+	if err != nil {
+		return false
+	}
+
+	if number, err := net.LookupPort(network, port); err != nil || (number != 0 && number != tcp.Port) {
+		return false
+	}
+	if host == "" {
+		return tcp.IP.IsUnspecified()
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.Equal(tcp.IP) || (ip.IsUnspecified() && tcp.IP.IsUnspecified())
+	}
+	return true
+}
+
+// listen binds the address, replacing a stale Unix socket file
+// and applying the socket mode and ownership
+func (c ListenerConfig) listen() (net.Listener, error) {
+	network := c.network()
+	if !strings.HasPrefix(network, "unix") {
+		return net.Listen(network, c.Address)
+	}
+
+	if err := removeStaleSocket(network, c.Address); err != nil {
+		return nil, err
+	}
+
+	// the socket is only accessible by the owner until the
+	// configured mode and ownership are applied
+	if c.Mode != 0 || c.Owner != "" || c.Group != "" {
+		restore := restrictUmask()
+		defer restore()
+	}
+
+	l, err := net.Listen(network, c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.chmod(); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes the socket file left by a process that
+// is no longer accepting connections, a socket still in use is an error
+func removeStaleSocket(network, path string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Type() != fs.ModeSocket {
+		return nil
+	}
+
+	conn, err := net.DialTimeout(network, path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("listen %s %s: socket is in use by another process", network, path)
+	}
+	if !connRefused(err) {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+func (c ListenerConfig) chmod() error {
+	if c.Mode != 0 {
+		if err := os.Chmod(c.Address, c.Mode); err != nil {
+			return err
+		}
+	}
+
+	if c.Owner == "" && c.Group == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	if c.Owner != "" {
+		u, err := lookupID(c.Owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return err
+		}
+		uid = u
+	}
+	if c.Group != "" {
+		g, err := lookupID(c.Group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return err
+		}
+		gid = g
+	}
+	return os.Chown(c.Address, uid, gid)
+}
+
+// lookupID returns the numeric id, or the id of the named user or group
+func lookupID(name string, lookup func(name string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	id, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
+// systemdListeners returns the listeners passed by socket activation,
+// none are returned when the sockets were passed to another process
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("systemd: invalid LISTEN_FDS: %w", err)
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	return fileListeners(listenFdsStart, count, names)
+}
+
+// fileListeners returns the listeners of count file descriptors from start
+func fileListeners(start, count int, names []string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("fd%d", start+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(start+i), name)
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("listener %s: %w", name, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
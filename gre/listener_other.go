@@ -0,0 +1,14 @@
+//go:build !unix
+
+package gre
+
+// restrictUmask is a no-op as this platform has no umask
+func restrictUmask() func() {
+	return func() {}
+}
+
+// connRefused never reports a socket as stale on this platform,
+// the socket file is left for the listener to fail on
+func connRefused(error) bool {
+	return false
+}
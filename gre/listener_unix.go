@@ -0,0 +1,29 @@
+//go:build unix
+
+package gre
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serialises the umask changes of the listeners being bound
+var umaskMu sync.Mutex
+
+// restrictUmask makes new files only accessible by the owner until
+// the returned function restores the umask, the umask is process wide
+func restrictUmask() func() {
+	umaskMu.Lock()
+	old := syscall.Umask(0o177)
+	return func() {
+		syscall.Umask(old)
+		umaskMu.Unlock()
+	}
+}
+
+// connRefused reports whether the dial error means no process is
+// accepting connections on the socket
+func connRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
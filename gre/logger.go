@@ -1,7 +1,10 @@
 package gre
 
 import (
-	"log"
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
@@ -13,21 +16,124 @@ import (
  * Created on: 27/04/2023 23:25
  */
 
+// responseRecorder wraps http.ResponseWriter to capture the
+// response status code and the number of body bytes written
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
 // Logger middleware will log all incoming request and the function that handled that request
+//
+// The request is logged after the handler completes with the response status,
+// size and the time taken to serve the request. Requests of handlers that
+// panic are logged with a 500 status unless the response has been written,
+// and the panic continues
 func Logger(inner http.Handler, name string) http.Handler {
+	return requestLogger(newAccessLog(slog.Default(), nil, nil, requestIDHeader), inner, name)
+}
+
+func requestLogger(access *accessLog, inner http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		recorder := newResponseRecorder(w)
+
+		defer func() {
+			value := recover()
+			entry := access.entry(r, recorder, name, start)
+			if value != nil && recorder.status == 0 {
+				entry.Status = http.StatusInternalServerError
+			}
+			access.log(r, entry)
+			if value != nil {
+				panic(value)
+			}
+		}()
 
-		log.Printf(
-			"%s %s %s %s %s %s",
-			r.Header.Get("X-Real-IP"),
-			r.Method,
-			r.RequestURI,
-			name,
-			time.Since(start),
-			r.UserAgent(),
-		)
-
-		inner.ServeHTTP(w, r)
+		inner.ServeHTTP(recorder, r)
 	})
 }
+
+// remoteIP returns the X-Real-IP header set by a reverse proxy,
+// otherwise the host of the connection remote address
+func remoteIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestID returns the request ID stored by the router,
+// otherwise the request ID header of the request
+//
+// param: <header> is the configured RequestIDConfig.Header
+func requestID(r *http.Request, header string) string {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(header)
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+// Status returns the response status code, a handler that
+// never calls WriteHeader responds with 200
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// WriteHeader records the first final status code, informational
+// responses such as 103 Early Hints precede the final response
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 && (code >= http.StatusOK || code == http.StatusSwitchingProtocols) {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher for streaming responses
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for protocol upgrades such as websockets
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not supported by the response writer")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter for http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
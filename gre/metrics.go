@@ -1,9 +1,15 @@
 package gre
 
 import (
+	"errors"
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 )
 
 /**
@@ -13,31 +19,273 @@ import (
  * Created on: 28/04/2023 23:06
  */
 
+const (
+	// unmatchedRoute is the route label of requests that
+	// didn't match any route, such as 404 and 405 responses
+	unmatchedRoute = "unmatched"
+
+	metricsPath = "/metrics"
+)
+
 var (
-	totalRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Number of get requests.",
-		},
-		[]string{"path"},
-	)
-
-	httpDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name: "http_response_time_seconds",
-			Help: "Duration of HTTP requests.",
-		}, []string{"path"},
-	)
+	// knownMethods limits the method label to the standard HTTP methods
+	knownMethods = map[string]bool{
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodPost:    true,
+		http.MethodPut:     true,
+		http.MethodPatch:   true,
+		http.MethodDelete:  true,
+		http.MethodConnect: true,
+		http.MethodOptions: true,
+		http.MethodTrace:   true,
+	}
+
+	defaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+
+	// fqNamePattern extracts the metric name of a prometheus.Desc description
+	fqNamePattern = regexp.MustCompile(`fqName: "([^"]+)"`)
 )
 
-func promMiddleware(next http.Handler) http.Handler {
+// httpMetrics is the built-in HTTP metric set of a router
+type httpMetrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	panics       *prometheus.CounterVec
+}
+
+// newHTTPMetrics creates and registers the HTTP metrics, metrics already
+// registered by another router with the same configuration are shared
+func newHTTPMetrics(cfg MetricsConfig, registerer prometheus.Registerer, logger *slog.Logger) *httpMetrics {
+	durationBuckets := cfg.DurationBuckets
+	if durationBuckets == nil {
+		durationBuckets = prometheus.DefBuckets
+	}
+	sizeBuckets := cfg.SizeBuckets
+	if sizeBuckets == nil {
+		sizeBuckets = defaultSizeBuckets
+	}
+	labels := []string{"route", "method", "code"}
+
+	return &httpMetrics{
+		requests: register(logger, registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: cfg.Namespace,
+				Subsystem: cfg.Subsystem,
+				Name:      "http_requests_total",
+				Help:      "Number of HTTP requests by route, method, status code and status class.",
+			}, []string{"route", "method", "code", "class"},
+		)),
+		duration: register(logger, registerer, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: cfg.Namespace,
+				Subsystem: cfg.Subsystem,
+				Name:      "http_response_time_seconds",
+				Help:      "Duration of HTTP requests.",
+				Buckets:   durationBuckets,
+			}, labels,
+		)),
+		requestSize: register(logger, registerer, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: cfg.Namespace,
+				Subsystem: cfg.Subsystem,
+				Name:      "http_request_size_bytes",
+				Help:      "Size of HTTP request bodies.",
+				Buckets:   sizeBuckets,
+			}, labels,
+		)),
+		responseSize: register(logger, registerer, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: cfg.Namespace,
+				Subsystem: cfg.Subsystem,
+				Name:      "http_response_size_bytes",
+				Help:      "Size of HTTP response bodies.",
+				Buckets:   sizeBuckets,
+			}, labels,
+		)),
+		inFlight: register(logger, registerer, prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: cfg.Namespace,
+				Subsystem: cfg.Subsystem,
+				Name:      "http_requests_in_flight",
+				Help:      "Number of HTTP requests currently being served.",
+			},
+		)),
+		panics: register(logger, registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: cfg.Namespace,
+				Subsystem: cfg.Subsystem,
+				Name:      "http_panics_total",
+				Help:      "Number of panics recovered while serving HTTP requests by route and method.",
+			}, []string{"route", "method"},
+		)),
+	}
+}
+
+func (c MetricsConfig) path() string {
+	if c.Path == "" {
+		return metricsPath
+	}
+	return c.Path
+}
+
+func (c MetricsConfig) registerer() prometheus.Registerer {
+	if c.Registerer == nil {
+		return prometheus.DefaultRegisterer
+	}
+	return c.Registerer
+}
+
+// gatherer returns the Gatherer of the metrics endpoint, a Registerer
+// that isn't a Gatherer requires the Gatherer to be set
+func (c MetricsConfig) gatherer() (prometheus.Gatherer, error) {
+	switch {
+	case c.Gatherer != nil:
+		return c.Gatherer, nil
+	case c.Registerer == nil:
+		return prometheus.DefaultGatherer, nil
+	}
+
+	if g, ok := c.Registerer.(prometheus.Gatherer); ok {
+		return g, nil
+	}
+	return nil, errors.New("metrics: MetricsConfig.Gatherer is required when the Registerer is not a prometheus.Gatherer")
+}
+
+// handler returns the metrics endpoint http.Handler for the Gatherer,
+// trace exemplars are exposed to clients accepting OpenMetrics
+func (c MetricsConfig) handler() (http.Handler, error) {
+	gatherer, err := c.gatherer()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := promhttp.HandlerOpts{EnableOpenMetrics: true}
+	if c.Gatherer == nil && c.Registerer == nil {
+		return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(gatherer, opts)), nil
+	}
+	return promhttp.HandlerFor(gatherer, opts), nil
+}
+
+// register registers the collector, or returns the equal
+// collector registered before it, such as by another router
+func register[T prometheus.Collector](logger *slog.Logger, registerer prometheus.Registerer, collector T) T {
+	if err := registerer.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				logger.Debug("reuse registered metrics collector", "metrics", metricNames(existing))
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
+}
+
+// metricNames returns the fully-qualified names of the collector metrics
+func metricNames(collector prometheus.Collector) []string {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(descs)
+		close(descs)
+	}()
+
+	var names []string
+	for desc := range descs {
+		if match := fqNamePattern.FindStringSubmatch(desc.String()); match != nil {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// middleware records the request metrics labelled by the matched
+// route path template, the request method and the response status code
+func (m *httpMetrics) middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newResponseRecorder(w)
 
-		timer := prometheus.NewTimer(httpDuration.WithLabelValues(r.URL.Path))
-		totalRequests.WithLabelValues(r.URL.Path).Inc()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
 
-		next.ServeHTTP(w, r)
+		// requests aborted by a panic are recorded before the panic
+		// continues, with a 500 status when no response was written
+		defer func() {
+			value := recover()
+			status := recorder.Status()
+			if value != nil && recorder.status == 0 {
+				status = http.StatusInternalServerError
+			}
+			m.record(r, status, recorder.bytes, start)
+			if value != nil {
+				panic(value)
+			}
+		}()
 
-		timer.ObserveDuration()
+		next.ServeHTTP(recorder, r)
 	})
 }
+
+// record records the metrics of a served request
+func (m *httpMetrics) record(r *http.Request, status, bytes int, start time.Time) {
+	code := strconv.Itoa(status)
+	labels := prometheus.Labels{
+		"route":  routeLabel(r),
+		"method": methodLabel(r.Method),
+		"code":   code,
+	}
+
+	requestSize := r.ContentLength
+	if requestSize < 0 {
+		requestSize = 0
+	}
+
+	traceID := exemplar(r.Context())
+	observe(m.duration.With(labels), time.Since(start).Seconds(), traceID)
+	observe(m.requestSize.With(labels), float64(requestSize), traceID)
+	observe(m.responseSize.With(labels), float64(bytes), traceID)
+
+	labels["class"] = code[:1] + "xx"
+
+	if traceID != nil {
+		m.requests.With(labels).(prometheus.ExemplarAdder).AddWithExemplar(1, traceID)
+	} else {
+		m.requests.With(labels).Inc()
+	}
+}
+
+// observe records the value with the trace exemplar when set
+func observe(observer prometheus.Observer, value float64, exemplar prometheus.Labels) {
+	if exemplar != nil {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(value, exemplar)
+		return
+	}
+	observer.Observe(value)
+}
+
+// routeLabel returns the path template of the matched route
+// example: "/user/{name}"
+func routeLabel(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return template
+}
+
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
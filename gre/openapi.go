@@ -0,0 +1,287 @@
+package gre
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+	openAPIPath    = "/openapi.json"
+
+	// the title and version of the API are required by OpenAPI
+	defaultOpenAPITitle   = "API"
+	defaultOpenAPIVersion = "0.0.0"
+)
+
+type (
+	openAPIDocument struct {
+		OpenAPI    string                          `json:"openapi"`
+		Info       openAPIDocumentInfo             `json:"info"`
+		Paths      map[string]map[string]operation `json:"paths"`
+		Components components                      `json:"components"`
+	}
+
+	openAPIDocumentInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	operation struct {
+		OperationID string                    `json:"operationId,omitempty"`
+		Summary     string                    `json:"summary,omitempty"`
+		Description string                    `json:"description,omitempty"`
+		Tags        []string                  `json:"tags,omitempty"`
+		Parameters  []parameter               `json:"parameters,omitempty"`
+		RequestBody *requestBody              `json:"requestBody,omitempty"`
+		Responses   map[string]responseObject `json:"responses"`
+		Deprecated  bool                      `json:"deprecated,omitempty"`
+	}
+
+	parameter struct {
+		Name     string `json:"name"`
+		In       string `json:"in"`
+		Required bool   `json:"required"`
+		Schema   Schema `json:"schema"`
+	}
+
+	requestBody struct {
+		Required bool                 `json:"required"`
+		Content  map[string]mediaType `json:"content"`
+	}
+
+	responseObject struct {
+		Description string               `json:"description"`
+		Content     map[string]mediaType `json:"content,omitempty"`
+	}
+
+	mediaType struct {
+		Schema Schema `json:"schema"`
+	}
+
+	components struct {
+		Schemas map[string]Schema `json:"schemas"`
+	}
+)
+
+// OpenAPI generates an OpenAPI 3.1 JSON document from the routes,
+// routes without any Methods are not included in the document
+//
+// param: <info> is the OpenAPIInfo for the document
+func (r *Routes) OpenAPI(info OpenAPIInfo) ([]byte, error) {
+	return r.openAPI(info, false)
+}
+
+func (i OpenAPIInfo) title() string {
+	if i.Title == "" {
+		return defaultOpenAPITitle
+	}
+	return i.Title
+}
+
+func (i OpenAPIInfo) version() string {
+	if i.Version == "" {
+		return defaultOpenAPIVersion
+	}
+	return i.Version
+}
+
+// openAPI generates the document using ProblemDetails as the
+// default error response when problem is true
+func (r *Routes) openAPI(info OpenAPIInfo, problem bool) ([]byte, error) {
+	errorResponse := mediaType{Schema: Schema{"$ref": "#/components/schemas/ErrorResponse"}}
+	errorContentType := "application/json"
+	if problem {
+		errorResponse = mediaType{Schema: Schema{"$ref": "#/components/schemas/ProblemDetails"}}
+		errorContentType = problemContentType
+	}
+
+	doc := openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIDocumentInfo{
+			Title:       info.title(),
+			Version:     info.version(),
+			Description: info.Description,
+		},
+		Paths: make(map[string]map[string]operation),
+		Components: components{
+			Schemas: map[string]Schema{
+				"ErrorResponse":  errorResponseSchema(),
+				"ProblemDetails": problemDetailsSchema(),
+			},
+		},
+	}
+
+	for _, route := range *r {
+		path, params := pathParams(route.Pattern)
+		for _, method := range route.Methods {
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]operation)
+			}
+			doc.Paths[path][strings.ToLower(method)] = newOperation(route, method, params, map[string]mediaType{errorContentType: errorResponse})
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+// newOperation returns the operation of the route method, the operationId
+// of routes with multiple methods is suffixed with the method to keep it unique
+// example: "UpdateUser_put"
+func newOperation(route Route, method string, params []parameter, errorContent map[string]mediaType) operation {
+	operationID := route.Name
+	if operationID != "" && len(route.Methods) > 1 {
+		operationID += "_" + strings.ToLower(method)
+	}
+
+	op := operation{
+		OperationID: operationID,
+		Summary:     route.Name,
+		Parameters:  params,
+		Deprecated:  route.Deprecated,
+		Responses: map[string]responseObject{
+			"default": {
+				Description: "Error response",
+				Content:     errorContent,
+			},
+		},
+	}
+
+	if route.Spec == nil {
+		return op
+	}
+
+	if route.Spec.Summary != "" {
+		op.Summary = route.Spec.Summary
+	}
+	op.Description = route.Spec.Description
+	op.Tags = route.Spec.Tags
+
+	if route.Spec.RequestBody != nil {
+		op.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{"application/json": {Schema: route.Spec.RequestBody}},
+		}
+	}
+
+	contentType := "application/json"
+	if route.ContentType != "" {
+		contentType = route.ContentType
+	}
+	for code, spec := range route.Spec.Responses {
+		resp := responseObject{Description: spec.Description}
+		if resp.Description == "" {
+			resp.Description = http.StatusText(code)
+		}
+		if spec.Schema != nil {
+			resp.Content = map[string]mediaType{contentType: {Schema: spec.Schema}}
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+
+	return op
+}
+
+// pathParams converts a mux path pattern to an OpenAPI path template
+// by removing the variable regular expressions, and returns the
+// path parameters found in the pattern
+func pathParams(pattern string) (string, []parameter) {
+	var (
+		path   strings.Builder
+		params []parameter
+	)
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '{' {
+			path.WriteByte(pattern[i])
+			continue
+		}
+
+		// variables may contain braces in their regular expression
+		level, end := 0, -1
+		for j := i; j < len(pattern) && end < 0; j++ {
+			switch pattern[j] {
+			case '{':
+				level++
+			case '}':
+				level--
+				if level == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			path.WriteString(pattern[i:])
+			break
+		}
+
+		name, regex, _ := strings.Cut(pattern[i+1:end], ":")
+		schema := Schema{"type": "string"}
+		if regex != "" {
+			schema["pattern"] = fmt.Sprintf("^%s$", regex)
+		}
+		params = append(params, parameter{Name: name, In: "path", Required: true, Schema: schema})
+		path.WriteString("{" + name + "}")
+		i = end
+	}
+
+	return path.String(), params
+}
+
+func errorResponseSchema() Schema {
+	return Schema{
+		"type":     "object",
+		"required": []string{"code", "cause"},
+		"properties": Schema{
+			"code":       Schema{"type": "integer", "description": "HTTP status code"},
+			"cause":      Schema{"type": "string", "description": "user-friendly error message"},
+			"debug":      Schema{"type": "string", "description": "additional information"},
+			"request_id": Schema{"type": "string", "description": "ID of the failed request"},
+		},
+	}
+}
+
+func problemDetailsSchema() Schema {
+	return Schema{
+		"type": "object",
+		"properties": Schema{
+			"type":     Schema{"type": "string", "format": "uri-reference", "default": "about:blank"},
+			"title":    Schema{"type": "string"},
+			"status":   Schema{"type": "integer"},
+			"detail":   Schema{"type": "string"},
+			"instance": Schema{"type": "string", "format": "uri-reference"},
+		},
+	}
+}
+
+func (s *Server) openAPIRoute() Route {
+	path := s.openAPI.Path
+	if path == "" {
+		path = openAPIPath
+	}
+
+	return Route{
+		Name:        "OpenAPI",
+		Methods:     []string{http.MethodGet},
+		Pattern:     path,
+		ContentType: "application/json",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			body, err := s.openAPIDoc, s.openAPIErr
+			if err != nil {
+				s.config.writeError(w, r, &ErrorResponse{
+					Code:  http.StatusInternalServerError,
+					Cause: "something went wrong, try again in few minutes",
+					Debug: "err: OpenAPI document generation failed",
+				})
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package gre
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// NewProblem returns ProblemDetails for the HTTP status code
+// with the standard status text as the title
+//
+// param: <status> is HTTP status code
+//
+// param: <detail> is the explanation of this occurrence of the problem
+func NewProblem(status int, detail string) *ProblemDetails {
+	return &ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WriteProblem writes the ProblemDetails to the response using
+// the application/problem+json content type. The Status defaults
+// to 500 and the Instance defaults to the request path
+//
+// param: <problem> is ProblemDetails definition of the error
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem *ProblemDetails) {
+	p := *problem
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" && p.Type == "about:blank" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" && r != nil {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write([]byte(p.Json()))
+}
+
+// Problem converts the ErrorResponse to ProblemDetails keeping the Debug
+// message and the RequestID as the "debug" and "request_id" extension members
+func (e *ErrorResponse) Problem() *ProblemDetails {
+	problem := NewProblem(e.Code, e.Cause)
+	if e.Debug != "" {
+		problem.Extensions = map[string]interface{}{"debug": e.Debug}
+	}
+	if e.RequestID != "" {
+		if problem.Extensions == nil {
+			problem.Extensions = map[string]interface{}{}
+		}
+		problem.Extensions["request_id"] = e.RequestID
+	}
+	return problem
+}
+
+// MarshalJSON encodes the ProblemDetails members and the Extensions
+// as a single JSON object, extensions can't replace the standard members
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	type standard ProblemDetails
+	body, err := json.Marshal(standard(p))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(members)
+}
+
+// Json encode ProblemDetails object for transmit.
+// returns a string
+func (p *ProblemDetails) Json() string {
+	body, err := json.Marshal(p)
+	if err != nil {
+		slog.Error("encode problem details", "status", p.Status, "error", err)
+		resp := ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Cause:   "something went wrong, try again in few minutes",
+			Debug:   "err: JSON encoding failed",
+			looping: true,
+		}
+		return resp.Problem().Json()
+	}
+	return string(body)
+}
@@ -0,0 +1,101 @@
+package gre
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"runtime/debug"
+)
+
+// UseRecovery configures the built-in recovery of routes that panic,
+// which is enabled by default
+//
+// param: <recovery> is RecoveryConfig definition of the recovery
+func (s *Server) UseRecovery(recovery RecoveryConfig) *Server {
+	s.config.recovery = recovery
+	return s
+}
+
+// WithRecovery configures the built-in recovery of router
+// routes that panic, which is enabled by default
+//
+// param: <recovery> is RecoveryConfig definition of the recovery
+func WithRecovery(recovery RecoveryConfig) RouterOption {
+	return func(c *routerConfig) {
+		c.recovery = recovery
+	}
+}
+
+// routerRecoverer recovers the panics of the router and group middleware,
+// panics of the route handlers are recovered by their own recoverer
+func (c routerConfig) routerRecoverer(next http.Handler) http.Handler {
+	return c.recoverer("", next)
+}
+
+// recoverer recovers the panics of the route handler, logs the stack,
+// reports the panic and responds with the RecoveryConfig.Response.
+// The name defaults to the name of the matched route.
+//
+// http.ErrAbortHandler is passed on to net/http, and so is any panic
+// once the response header has been written as the error can no longer
+// be returned and the connection is aborted instead
+func (c routerConfig) recoverer(name string, next http.Handler) http.Handler {
+	if c.recovery.Disabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := newResponseRecorder(w)
+
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+
+			route := name
+			if current := mux.CurrentRoute(r); route == "" && current != nil {
+				route = current.GetName()
+			}
+
+			report := &PanicReport{
+				Route:     route,
+				RequestID: requestID(r, c.requestID.header()),
+				Value:     value,
+				Stack:     debug.Stack(),
+			}
+			attrs := []any{"route", report.Route, "request_id", report.RequestID}
+			if traceID, spanID := spanIDs(r.Context()); traceID != "" {
+				attrs = append(attrs, "trace_id", traceID, "span_id", spanID)
+			}
+			c.log().ErrorContext(r.Context(), "panic recovered",
+				append(attrs, "panic", fmt.Sprint(value), "stack", string(report.Stack))...,
+			)
+			recordPanic(r.Context(), report)
+			if c.httpMetrics != nil {
+				c.httpMetrics.panics.WithLabelValues(routeLabel(r), methodLabel(r.Method)).Inc()
+			}
+			if c.recovery.Reporter != nil {
+				c.recovery.Reporter(r, report)
+			}
+
+			if recorder.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+
+			resp := c.recovery.Response
+			if resp == nil {
+				resp = &ErrorResponse{
+					Code:  http.StatusInternalServerError,
+					Cause: "something went wrong, try again in few minutes",
+				}
+			}
+			c.writeError(w, r, resp)
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,115 @@
+package gre
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength limits the length of request IDs accepted from clients
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// UseRequestID configures the request IDs, which are enabled by default
+//
+// param: <config> is RequestIDConfig definition of the request IDs
+func (s *Server) UseRequestID(config RequestIDConfig) *Server {
+	s.config.requestID = config
+	return s
+}
+
+// WithRequestID configures the router request IDs, which are enabled by default
+//
+// param: <config> is RequestIDConfig definition of the request IDs
+func WithRequestID(config RequestIDConfig) RouterOption {
+	return func(c *routerConfig) {
+		c.requestID = config
+	}
+}
+
+// RequestIDFromContext returns the ID of the request being served,
+// an empty string is returned when request IDs are disabled
+//
+// param: <ctx> is the request context
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random time ordered UUIDv7
+// example: "0192a4c8-6f3e-7b1a-9c2d-4e5f6a7b8c9d"
+func NewRequestID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[6:])
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(uuid[:6], ms[2:])
+
+	uuid[6] = uuid[6]&0x0f | 0x70
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
+
+func (c RequestIDConfig) header() string {
+	if c.Header == "" {
+		return requestIDHeader
+	}
+	return c.Header
+}
+
+// middleware stores the request ID in the request context and
+// returns it in the response header, IDs that are too long or
+// contain non-printable characters are replaced
+func (c RequestIDConfig) middleware(next http.Handler) http.Handler {
+	if c.Disabled {
+		return next
+	}
+
+	generate := c.Generate
+	if generate == nil {
+		generate = NewRequestID
+	}
+	header := c.header()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if !validRequestID(id) {
+			id = generate()
+		}
+
+		w.Header().Set(header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
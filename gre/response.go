@@ -0,0 +1,128 @@
+package gre
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// defaultsWriter wraps http.ResponseWriter to set the default
+// headers before the response header is written
+type defaultsWriter struct {
+	http.ResponseWriter
+	contentType string
+	headers     http.Header
+	written     bool
+}
+
+// UseResponseDefaults sets the default content type, charset and headers
+// of the responses, Route.ContentType overrides the default content type
+//
+// param: <defaults> is ResponseDefaults definition of the response headers
+func (s *Server) UseResponseDefaults(defaults ResponseDefaults) *Server {
+	s.config.defaults = defaults
+	return s
+}
+
+// WithResponseDefaults sets the default content type, charset
+// and headers of the router responses
+//
+// param: <defaults> is ResponseDefaults definition of the response headers
+func WithResponseDefaults(defaults ResponseDefaults) RouterOption {
+	return func(c *routerConfig) {
+		c.defaults = defaults
+	}
+}
+
+// handler applies the defaults to the responses of the handler,
+// contentType replaces the default content type when set
+func (d ResponseDefaults) handler(contentType string, next http.Handler) http.Handler {
+	if contentType == "" {
+		contentType = d.ContentType
+	}
+	if contentType == "" && len(d.Headers) == 0 {
+		return next
+	}
+
+	contentType = d.withCharset(contentType)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&defaultsWriter{ResponseWriter: w, contentType: contentType, headers: d.Headers}, r)
+	})
+}
+
+// withCharset adds the Charset to text, JSON and XML content types
+// that don't have a charset parameter
+func (d ResponseDefaults) withCharset(contentType string) string {
+	if d.Charset == "" || contentType == "" {
+		return contentType
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] != "" {
+		return contentType
+	}
+	if !strings.HasPrefix(mediaType, "text/") &&
+		!strings.HasSuffix(mediaType, "json") && !strings.HasSuffix(mediaType, "xml") {
+		return contentType
+	}
+	return contentType + "; charset=" + d.Charset
+}
+
+// apply sets the defaults the handler hasn't set, responses
+// without a body don't get a content type
+func (w *defaultsWriter) apply(code int) {
+	// informational responses are followed by the final response header
+	if w.written || (code < http.StatusOK && code != http.StatusSwitchingProtocols) {
+		return
+	}
+	w.written = true
+
+	header := w.Header()
+	for name, values := range w.headers {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+
+	bodyless := code == http.StatusSwitchingProtocols || code == http.StatusNoContent || code == http.StatusNotModified
+	if w.contentType != "" && !bodyless {
+		if _, ok := header["Content-Type"]; !ok {
+			header.Set("Content-Type", w.contentType)
+		}
+	}
+}
+
+func (w *defaultsWriter) WriteHeader(code int) {
+	w.apply(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *defaultsWriter) Write(b []byte) (int, error) {
+	w.apply(http.StatusOK)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streaming responses
+func (w *defaultsWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.apply(http.StatusOK)
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for protocol upgrades such as websockets
+func (w *defaultsWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not supported by the response writer")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter for http.ResponseController
+func (w *defaultsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
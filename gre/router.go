@@ -3,8 +3,8 @@ package gre
 import (
 	"fmt"
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 )
 
@@ -21,86 +21,189 @@ import (
 // param: <routes> a Routes object with list of Route objects
 //
 // param: <strictSlashes> defines the trailing slash behavior for new routes
-func NewRouter(routes Routes, strictSlashes bool) *mux.Router {
-	return addRoutes(routes, strictSlashes)
+//
+// param: <options> are optional RouterOption such as WithLogger
+func NewRouter(routes Routes, strictSlashes bool, options ...RouterOption) *mux.Router {
+	cfg := routerConfig{strictSlash: strictSlashes}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return addRoutes(routes, nil, cfg)
+}
+
+// WithLogger sets the slog.Logger used for route registration
+// and request logs, defaults to slog.Default
+//
+// param: <logger> is the slog.Logger for the router
+func WithLogger(logger *slog.Logger) RouterOption {
+	return func(c *routerConfig) {
+		c.logger = logger
+	}
+}
+
+// WithAccessLog writes request logs formatted by the AccessLogFormat
+// to the io.Writer instead of the router slog.Logger
+//
+// param: <format> is AccessLogFormat such as CombinedLogFormat
+//
+// param: <out> is the log destination, defaults to os.Stdout
+func WithAccessLog(format AccessLogFormat, out io.Writer) RouterOption {
+	return func(c *routerConfig) {
+		c.accessLogFormat = format
+		c.accessLogOutput = out
+	}
+}
+
+// WithMetrics configures the built-in HTTP metrics
+//
+// param: <metrics> is MetricsConfig definition of the metrics
+func WithMetrics(metrics MetricsConfig) RouterOption {
+	return func(c *routerConfig) {
+		c.metrics = metrics
+	}
 }
 
-func addRoutes(routes Routes, strict bool) *mux.Router {
-	router := mux.NewRouter().StrictSlash(strict)
+// WithProblemDetails makes the router respond to 404, 405 and
+// deprecated routes with RFC 9457 ProblemDetails
+func WithProblemDetails() RouterOption {
+	return func(c *routerConfig) {
+		c.problemDetails = true
+	}
+}
 
-	log.Println("add global handler 404 - not found")
-	router.NotFoundHandler = http.HandlerFunc(add404)
+func addRoutes(routes Routes, groups RouteGroups, cfg routerConfig) *mux.Router {
+	router := mux.NewRouter().StrictSlash(cfg.strictSlash)
+	logger := cfg.log()
+	cfg.access = newAccessLog(logger, cfg.accessLogFormat, cfg.accessLogOutput, cfg.requestID.header())
+	metrics := newHTTPMetrics(cfg.metrics, cfg.metrics.registerer(), logger)
+	cfg.httpMetrics = metrics
+	tracing := newTracing(cfg.tracing)
 
-	log.Println("add global handler 405 - method not allowed")
-	router.MethodNotAllowedHandler = http.HandlerFunc(add405)
+	logger.Info("add global handler", "code", http.StatusNotFound, "handler", "not found")
+	router.NotFoundHandler = cfg.requestID.middleware(tracing.middleware(metrics.middleware(cfg.defaults.handler("", cfg.errorHandler(http.StatusNotFound, "resource not found")))))
 
-	router.
-		Name("Prometheus metrics").
-		Methods(http.MethodGet).
-		Path("/metrics").
-		Handler(Logger(promhttp.Handler(), "Prometheus metrics"))
-	log.Println("add mapping: Prometheus metrics ( [GET] /metrics )")
+	// preflight requests of routes without OPTIONS don't match any route
+	cfg.preflight = newCORSPreflight(router)
 
-	for _, route := range routes {
-		var handler http.Handler
-		if route.Deprecated {
-			log.Printf("ignore mapping: %s ( %s %s ) deprecated\n", route.Name, route.Methods, route.Pattern)
-			handler = http.HandlerFunc(deprecated)
+	logger.Info("add global handler", "code", http.StatusMethodNotAllowed, "handler", "method not allowed")
+	router.MethodNotAllowedHandler = cfg.requestID.middleware(tracing.middleware(metrics.middleware(cfg.defaults.handler("", cfg.preflight.handler(cfg.errorHandler(http.StatusMethodNotAllowed, "method not allowed"))))))
+
+	if !cfg.metrics.DisableEndpoint && cfg.metrics.Addr == "" {
+		if handler, err := cfg.metrics.handler(); err != nil {
+			logger.Error("ignore mapping", "name", "Prometheus metrics", "pattern", cfg.metrics.path(), "error", err)
 		} else {
-			handler = route.HandlerFunc
+			router.
+				Name("Prometheus metrics").
+				Methods(http.MethodGet).
+				Path(cfg.metrics.path()).
+				Handler(requestLogger(cfg.access, handler, "Prometheus metrics"))
+			logger.Info("add mapping", "name", "Prometheus metrics", "methods", []string{http.MethodGet}, "pattern", cfg.metrics.path())
 		}
+	}
 
-		handler = Logger(handler, route.Name)
-		router.
-			Methods(route.Methods...).
-			Path(route.Pattern).
-			Name(route.Name).
-			Handler(handler)
+	for _, route := range routes {
+		addRoute(router, route, "", cfg)
+	}
 
-		log.Printf("add mapping: %s ( %s %s )\n", route.Name, route.Methods, route.Pattern)
+	for _, group := range groups {
+		subrouter := router.PathPrefix(group.Prefix).Subrouter()
+		for _, middleware := range group.Middlewares {
+			subrouter.Use(middleware)
+		}
+		logger.Info("add group", "prefix", group.Prefix, "middlewares", len(group.Middlewares))
+
+		for _, route := range group.Routes {
+			addRoute(subrouter, route, group.Prefix, cfg)
+		}
 	}
 
-	router.Use(mux.CORSMethodMiddleware(router))
-	router.Use(promMiddleware)
+	if cfg.corsMethods {
+		router.Use(mux.CORSMethodMiddleware(router))
+	}
+	router.Use(cfg.requestID.middleware)
+	router.Use(tracing.middleware)
+	router.Use(metrics.middleware)
+	router.Use(cfg.routerRecoverer)
+	router.Use(clientIdentity)
 
 	return router
 }
 
-func health(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	resp := response{
-		Code:   http.StatusOK,
-		Status: "Alive",
+// addRoute registers a single Route on the router, prefix is only
+// used for logging the full path of routes added to a subrouter
+func addRoute(router *mux.Router, route Route, prefix string, cfg routerConfig) {
+	logger := cfg.log()
+
+	var handler http.Handler
+	if route.Deprecated {
+		logger.Warn("ignore mapping", "name", route.Name, "methods", route.Methods, "pattern", prefix+route.Pattern, "reason", "deprecated")
+		handler = cfg.errorHandler(http.StatusForbidden, "method deprecated")
+	} else {
+		handler = route.HandlerFunc
 	}
-	fmt.Fprint(w, resp.json())
-}
 
-func add404(w http.ResponseWriter, r *http.Request) {
-	resp := &ErrorResponse{
-		Code:  http.StatusNotFound,
-		Cause: "resource not found",
+	handler = chain(handler, route.Middlewares)
+	if len(route.AllowedClients) > 0 {
+		handler = cfg.allowClients(route.AllowedClients, handler)
+	}
+	muxRoute := router.
+		Methods(route.Methods...).
+		Path(route.Pattern).
+		Name(route.Name)
+	if policy := cfg.preflight.add(muxRoute, route, cfg.cors); policy != nil {
+		handler = policy.middleware(cfg.preflight, handler)
 	}
+	handler = cfg.recoverer(route.Name, handler)
+	handler = cfg.defaults.handler(route.ContentType, handler)
+	handler = requestLogger(cfg.access, handler, route.Name)
+	muxRoute.Handler(handler)
 
-	w.WriteHeader(resp.Code)
-	fmt.Fprint(w, resp.Json())
+	logger.Info("add mapping", "name", route.Name, "methods", route.Methods, "pattern", prefix+route.Pattern)
 }
 
-func add405(w http.ResponseWriter, r *http.Request) {
-	resp := &ErrorResponse{
-		Code:  http.StatusMethodNotAllowed,
-		Cause: "method not allowed",
+// chain wraps the handler with the middlewares so that
+// the first middleware in the list is the first to run
+func chain(handler http.Handler, middlewares []func(http.Handler) http.Handler) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
 	}
+	return handler
+}
 
-	w.WriteHeader(resp.Code)
-	fmt.Fprint(w, resp.Json())
+// log returns the router slog.Logger or slog.Default when not set
+func (c routerConfig) log() *slog.Logger {
+	if c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
 }
 
-func deprecated(w http.ResponseWriter, r *http.Request) {
-	resp := &ErrorResponse{
-		Code:  http.StatusForbidden,
-		Cause: "method deprecated",
+// errorHandler returns a http.HandlerFunc responding with
+// the framework error response for the given status code
+func (c routerConfig) errorHandler(code int, cause string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.writeError(w, r, &ErrorResponse{
+			Code:  code,
+			Cause: cause,
+		})
+	}
+}
+
+// writeError writes the ErrorResponse, or its ProblemDetails
+// equivalent when problem details are enabled, with the request ID
+func (c routerConfig) writeError(w http.ResponseWriter, r *http.Request, resp *ErrorResponse) {
+	if resp.RequestID == "" {
+		withID := *resp
+		withID.RequestID = requestID(r, c.requestID.header())
+		resp = &withID
+	}
+
+	if c.problemDetails {
+		WriteProblem(w, r, resp.Problem())
+		return
 	}
 
+	w.Header().Set("Content-Type", c.defaults.withCharset("application/json"))
 	w.WriteHeader(resp.Code)
 	fmt.Fprint(w, resp.Json())
 }
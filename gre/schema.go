@@ -1,10 +1,24 @@
 package gre
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
-	"errors"
-	"log"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http2"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 /**
@@ -40,6 +54,132 @@ type (
 		// HandlerFunc is an adapter to allow the use of
 		// ordinary functions as HTTP handlers.
 		HandlerFunc http.HandlerFunc
+
+		// Middlewares wrap the HandlerFunc of this route only, the
+		// first middleware in the list is the first to run.
+		//
+		// Route middlewares run after the router middleware
+		// (HTTP metrics), the RouteGroup middleware, the Logger, the
+		// panic recovery, CORS and the AllowedClients check and before
+		// the HandlerFunc
+		Middlewares []func(http.Handler) http.Handler
+
+		// Spec is optional OpenAPI metadata for the route
+		Spec *OperationSpec
+
+		// AllowedClients restricts the route to mutual TLS clients with an
+		// identity matching one of the patterns. Each pattern is only matched
+		// against its own identity type:
+		//   - URIs such as SPIFFE IDs match the URI SANs, a trailing "*" matches any suffix
+		//   - email addresses match the email SANs, "*@domain" matches any mailbox
+		//   - "CN=" prefixed names match the subject common name
+		//   - other names match the DNS SANs, a leading "*." matches a single label
+		// example: []string{"spiffe://example.org/ns/prod/sa/billing", "spiffe://example.org/ns/ops/*"}
+		AllowedClients []string
+
+		// CORS overrides the server CORSConfig for this route,
+		// an empty CORSConfig disables CORS for the route
+		CORS *CORSConfig
+
+		// ContentType is the response content type of the route, set on
+		// responses without a Content-Type instead of ResponseDefaults.ContentType
+		// example: "application/json"
+		ContentType string
+	}
+
+	// ClientIdentity is the identity of a verified mutual TLS client certificate
+	ClientIdentity struct {
+
+		// Subject is the certificate subject distinguished name
+		Subject string
+
+		// CommonName is the certificate subject common name
+		CommonName string
+
+		// DNSNames are the DNS subject alternative names
+		DNSNames []string
+
+		// EmailAddresses are the email subject alternative names
+		EmailAddresses []string
+
+		// URIs are the URI subject alternative names
+		URIs []string
+
+		// SPIFFEID is the first spiffe:// URI subject alternative name
+		SPIFFEID string
+
+		// Certificate is the verified client certificate
+		Certificate *x509.Certificate
+	}
+
+	// OperationSpec describes a Route in the generated OpenAPI document
+	OperationSpec struct {
+
+		// Summary is a short summary of what the route does
+		Summary string
+
+		// Description is a verbose explanation of the route behavior
+		Description string
+
+		// Tags are used for logical grouping of routes
+		Tags []string
+
+		// RequestBody is the JSON Schema of the request body
+		RequestBody Schema
+
+		// Responses maps HTTP status codes to the response returned
+		Responses map[int]ResponseSpec
+	}
+
+	// ResponseSpec describes a single response of a Route
+	ResponseSpec struct {
+
+		// Description of the response
+		Description string
+
+		// Schema is the JSON Schema of the response body
+		Schema Schema
+	}
+
+	// Schema is a JSON Schema object
+	// example: Schema{"type": "object", "properties": Schema{"message": Schema{"type": "string"}}}
+	Schema map[string]interface{}
+
+	// OpenAPIInfo configures the OpenAPI document generated from the routes
+	OpenAPIInfo struct {
+
+		// Path the document is served on, defaults to "/openapi.json"
+		Path string
+
+		// Title of the API, defaults to "API"
+		Title string
+
+		// Version of the API, defaults to "0.0.0"
+		Version string
+
+		// Description of the API
+		Description string
+	}
+
+	// RouteGroups defines a collection of RouteGroup
+	RouteGroups []RouteGroup
+
+	// RouteGroup defines a collection of Route served under a
+	// common path prefix using a mux.Router subrouter
+	RouteGroup struct {
+
+		// Prefix is the URI path prefix shared by all the routes
+		// in the group
+		// example: "/api/v1"
+		Prefix string
+
+		// Middlewares are applied only to requests matching
+		// one of the routes in the group
+		Middlewares []func(http.Handler) http.Handler
+
+		// Routes are the group routes with their Pattern relative
+		// to the Prefix
+		Routes Routes
 	}
 
 	// Server extends http.Server with few additional parameters
@@ -52,10 +192,374 @@ type (
 		// router globally
 		middlewares []func(http.Handler) http.Handler
 
+		// routes is the server's own route table
+		routes Routes
+
+		// groups are the server's route groups
+		groups RouteGroups
+
+		// useRouteTable imports the package level RouteTable on Build
+		useRouteTable bool
+
+		// openAPI is the OpenAPI document configuration, the document
+		// is only served when set
+		openAPI *OpenAPIInfo
+
+		// openAPIDoc is the OpenAPI document of the routes built by Build
+		openAPIDoc []byte
+		openAPIErr error
+
+		// metricsServer serves the metrics endpoint when
+		// MetricsConfig.Addr is set
+		metricsServer *http.Server
+
+		// shutdown configures the graceful shutdown
+		shutdown ShutdownConfig
+
+		// draining is set once Stop is invoked
+		draining atomic.Bool
+
+		// inFlight is the number of requests being served
+		inFlight atomic.Int64
+
+		// hooks are the lifecycle hooks
+		hooks lifecycleHooks
+
+		// shutdownOnce guards the OnShutdown hooks
+		shutdownOnce sync.Once
+
+		// shutdownErr is the result of the OnShutdown hooks
+		shutdownErr error
+
+		// healthChecks are run by the health probes
+		healthChecks []HealthCheck
+
+		// probes enables the health probes when set
+		probes *ProbesConfig
+
+		// started is set once the server is listening and the
+		// OnReady hooks have completed
+		started atomic.Bool
+
+		// tls enables HTTPS when set
+		tls *TLSConfig
+
+		// certs is the certificate reloader of the listening HTTPS server
+		certs *certReloader
+
+		// stopWatch stops the certificate reloader
+		stopWatch context.CancelFunc
+
+		// http2 configures the HTTP/2 server when set
+		http2 *HTTP2Config
+
+		// http2Server is the HTTP/2 server registered on the http.Server
+		http2Server *http2.Server
+
+		// listeners are the addresses bound in addition to Addr
+		listeners []ListenerConfig
+
+		// systemd enables the listeners passed by socket activation
+		systemd bool
+
+		// bound are the listeners the server is serving on
+		bound []net.Listener
+
+		// metricsBound is the listener of the metrics server
+		metricsBound net.Listener
+
+		// upgrade enables upgrades to a new process when set
+		upgrade *UpgradeConfig
+
+		// upgrading guards against concurrent upgrades
+		upgrading atomic.Bool
+
+		// config is passed to the router on Build
+		config routerConfig
+
+		// buildErr is the route conflict found by Build
+		buildErr error
+
+		// builtRoutes identifies the routes of the handler built by Build,
+		// empty until the server is built
+		builtRoutes string
+
 		http.Server
 	}
 
+	// Probe identifies a health endpoint
+	Probe string
+
+	// ProbesConfig configures the paths of the health probes, a probe
+	// is not served when a route already uses its path or route name
+	ProbesConfig struct {
+
+		// LivenessPath defaults to /livez
+		LivenessPath string
+
+		// ReadinessPath defaults to /readyz
+		ReadinessPath string
+
+		// StartupPath defaults to /startupz
+		StartupPath string
+	}
+
+	// HealthCheck defines a named check run by the health probes
+	HealthCheck struct {
+
+		// Name of the check reported in the probe response
+		Name string
+
+		// Check returns an error when the checked dependency is unhealthy
+		Check func(ctx context.Context) error
+
+		// Timeout of the check, defaults to 1 second
+		Timeout time.Duration
+
+		// Critical checks fail the probe, failures of non-critical
+		// checks are only reported
+		Critical bool
+
+		// Probes the check is run by, defaults to ReadinessProbe
+		Probes []Probe
+	}
+
+	// TLSConfig configures HTTPS for a Server, the http.Server TLSConfig
+	// when set is the base of the served configuration
+	TLSConfig struct {
+
+		// CertFile is the PEM encoded certificate chain
+		CertFile string
+
+		// KeyFile is the PEM encoded private key
+		KeyFile string
+
+		// MinVersion is the minimum TLS version, defaults to tls.VersionTLS12
+		MinVersion uint16
+
+		// CipherSuites are the enabled TLS 1.0-1.2 cipher suites,
+		// defaults to the crypto/tls secure cipher suites
+		CipherSuites []uint16
+
+		// ClientCAFile is the PEM encoded CA bundle used to verify
+		// client certificates for mutual TLS
+		ClientCAFile string
+
+		// ClientAuth is the client certificate policy, defaults to
+		// tls.RequireAndVerifyClientCert when ClientCAFile is set
+		ClientAuth tls.ClientAuthType
+
+		// ReloadInterval is how often the files are checked for changes,
+		// defaults to 30 seconds and a negative value disables reloading
+		ReloadInterval time.Duration
+	}
+
+	// ListenerConfig defines an address a Server listens on
+	ListenerConfig struct {
+
+		// Network is "tcp", "tcp4", "tcp6" or "unix", defaults to "tcp"
+		Network string
+
+		// Address is the host:port of a TCP listener or
+		// the socket file path of a Unix listener, a stale socket file
+		// is replaced while a socket in use fails the listener
+		Address string
+
+		// Mode is the file mode of the Unix socket, such as 0660
+		Mode os.FileMode
+
+		// Owner is the user name or id owning the Unix socket
+		Owner string
+
+		// Group is the group name or id owning the Unix socket
+		Group string
+	}
+
+	// UpgradeConfig configures the zero-downtime upgrade of a Server
+	// to a new process of the executable inheriting its listeners
+	UpgradeConfig struct {
+
+		// Signals start the upgrade, defaults to SIGHUP and SIGUSR2
+		Signals []os.Signal
+
+		// ReadyTimeout is the time given to the new process to
+		// report ready, defaults to 30 seconds
+		ReadyTimeout time.Duration
+	}
+
+	// HTTP2Config configures HTTP/2 for a Server
+	HTTP2Config struct {
+
+		// H2C serves HTTP/2 over cleartext connections, using prior
+		// knowledge or the HTTP/1.1 Upgrade header, alongside HTTP/1.1
+		H2C bool
+
+		// MaxConcurrentStreams is the number of concurrent streams
+		// per connection, defaults to 250
+		MaxConcurrentStreams uint32
+
+		// MaxReadFrameSize is the largest frame accepted from the client
+		// between 16KiB and 16MiB, defaults to 1MiB
+		MaxReadFrameSize uint32
+
+		// IdleTimeout closes connections without active streams,
+		// defaults to the http.Server IdleTimeout
+		IdleTimeout time.Duration
+	}
+
+	// Hook is a Server lifecycle callback
+	Hook func(ctx context.Context) error
+
+	// lifecycleHooks are the hooks registered for each Server lifecycle phase
+	lifecycleHooks struct {
+		start    []Hook
+		ready    []Hook
+		shutdown []Hook
+		stopped  []Hook
+	}
+
+	// ShutdownConfig configures the graceful shutdown of a Server
+	ShutdownConfig struct {
+
+		// DrainTimeout is the time in-flight requests are given
+		// to complete, defaults to 5 seconds
+		DrainTimeout time.Duration
+
+		// PreShutdownDelay is the time the /health endpoint fails
+		// before the listeners are closed, allowing load balancers
+		// to deregister the server
+		PreShutdownDelay time.Duration
+	}
+
+	// DrainTimeoutError is returned by Stop when requests are still
+	// in flight once the DrainTimeout is exceeded
+	DrainTimeoutError struct {
+
+		// Timeout is the DrainTimeout that was exceeded
+		Timeout time.Duration
+
+		// InFlight is the number of requests still in flight
+		InFlight int64
+	}
+
+	// CORSConfig configures Cross-Origin Resource Sharing, the methods
+	// allowed by preflight requests are the methods registered for the path
+	CORSConfig struct {
+
+		// AllowedOrigins are the origins allowed to make cross-origin requests,
+		// "*" allows any origin and a "*." host prefix allows any subdomain.
+		// CORS is disabled when neither origins nor patterns are set
+		// example: []string{"https://example.com", "https://*.example.com"}
+		AllowedOrigins []string
+
+		// AllowedOriginPatterns are regular expressions matched against the
+		// whole origin, they are anchored with ^ and $ when the router is built
+		// example: regexp.MustCompile(`https://[a-z]+\.example\.com`)
+		AllowedOriginPatterns []*regexp.Regexp
+
+		// AllowedMethods restricts the registered methods allowed
+		// by preflight requests, defaults to all of them
+		AllowedMethods []string
+
+		// AllowedHeaders are the request headers allowed by preflight requests,
+		// "*" allows any header. Defaults to Accept, Accept-Language,
+		// Content-Language, Content-Type and Authorization
+		AllowedHeaders []string
+
+		// ExposedHeaders are the response headers readable by the client
+		ExposedHeaders []string
+
+		// AllowCredentials allows requests with cookies or HTTP authentication,
+		// the request origin is returned instead of "*"
+		AllowCredentials bool
+
+		// MaxAge is how long preflight responses can be cached,
+		// a negative value disables caching
+		MaxAge time.Duration
+	}
+
+	// ResponseDefaults are applied to the responses of all the
+	// routes, including the 404, 405 and deprecated route responses,
+	// unless the handler sets them
+	ResponseDefaults struct {
+
+		// ContentType is set on responses without a Content-Type
+		// example: "application/json"
+		ContentType string
+
+		// Charset is added to text, JSON and XML content types without a charset
+		// example: "utf-8"
+		Charset string
+
+		// Headers are set on responses without the header
+		Headers http.Header
+	}
+
+	// RecoveryConfig configures the built-in recovery of routes, group and
+	// route middleware that panic, the panic is logged with its stack and an
+	// ErrorResponse is returned. Panics in the server middleware added with
+	// Server.AddMiddleware run outside the router and are not recovered
+	RecoveryConfig struct {
+
+		// Disabled leaves the panics to be handled by net/http
+		Disabled bool
+
+		// Response is returned to the client, defaults to a 500 ErrorResponse
+		Response *ErrorResponse
+
+		// Reporter is invoked with every recovered panic, such as
+		// for sending the panic to an error tracking service
+		Reporter func(r *http.Request, report *PanicReport)
+	}
+
+	// RequestIDConfig configures the request ID read from the request
+	// header or generated, the request ID is returned in the same header
+	RequestIDConfig struct {
+
+		// Disabled turns off the request IDs, the logs and error
+		// responses then report the Header of the request as is
+		Disabled bool
+
+		// Header carries the request ID, defaults to X-Request-ID
+		Header string
+
+		// Generate returns the ID of requests without a valid request ID,
+		// defaults to NewRequestID
+		Generate func() string
+	}
+
+	// TracingConfig configures the OpenTelemetry server spans of the routes,
+	// spans are named after the Route.Name or the route path template
+	TracingConfig struct {
+
+		// TracerProvider creates the tracer, defaults to otel.GetTracerProvider
+		TracerProvider trace.TracerProvider
+
+		// Propagator extracts the parent trace context from the request
+		// headers, defaults to otel.GetTextMapPropagator
+		Propagator propagation.TextMapPropagator
+	}
+
+	// PanicReport describes a panic recovered while serving a request
+	PanicReport struct {
+
+		// Route is the name of the route that panicked
+		Route string
+
+		// RequestID of the request
+		RequestID string
+
+		// Value is the value passed to panic
+		Value interface{}
+
+		// Stack is the stack trace of the panicking goroutine
+		Stack []byte
+	}
+
 	// HttpResponseConfig is built in CORS configurator
+	//
+	// Deprecated: use CORSConfig with Server.UseCORS and
+	// ResponseDefaults with Server.UseResponseDefaults
 	HttpResponseConfig struct {
 
 		// ContextType is the HTTP response format
@@ -86,24 +590,217 @@ type (
 		// Debug is for additional information if needed
 		Debug string `json:"debug,omitempty"`
 
+		// RequestID of the failed request, set by the router
+		RequestID string `json:"request_id,omitempty"`
+
 		looping bool
 	}
 
+	// ProblemDetails defines the RFC 9457 error response structure
+	// for HTTP requests, served as application/problem+json
+	ProblemDetails struct {
+
+		// Type is a URI reference identifying the problem type,
+		// defaults to "about:blank"
+		Type string `json:"type,omitempty"`
+
+		// Title is a short human-readable summary of the problem type
+		Title string `json:"title,omitempty"`
+
+		// Status is HTTP status code
+		Status int `json:"status,omitempty"`
+
+		// Detail is a human-readable explanation specific to
+		// this occurrence of the problem
+		Detail string `json:"detail,omitempty"`
+
+		// Instance is a URI reference identifying this occurrence
+		// of the problem
+		Instance string `json:"instance,omitempty"`
+
+		// Extensions are additional members added to the top
+		// level of the problem JSON object
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// routerConfig defines the options used to build the mux.Router
+	routerConfig struct {
+
+		// strictSlash defines the trailing slash behavior for new routes
+		strictSlash bool
+
+		// problemDetails enables RFC 9457 framework error responses
+		problemDetails bool
+
+		// logger is used for route registration and request logs
+		logger *slog.Logger
+
+		// accessLogFormat formats request logs written to accessLogOutput
+		// instead of the logger when set
+		accessLogFormat AccessLogFormat
+
+		// accessLogOutput is the destination of formatted request logs
+		accessLogOutput io.Writer
+
+		// access is the request logger shared by all the routes
+		access *accessLog
+
+		// metrics configures the built-in HTTP metrics
+		metrics MetricsConfig
+
+		// cors is the CORSConfig of routes without their own
+		cors *CORSConfig
+
+		// preflight answers CORS preflight requests for all the routes
+		preflight *corsPreflight
+
+		// corsMethods applies mux.CORSMethodMiddleware for AddCORSHandler
+		corsMethods bool
+
+		// defaults are applied to all the responses
+		defaults ResponseDefaults
+
+		// recovery configures the recovery of panicking routes
+		recovery RecoveryConfig
+
+		// requestID configures the request IDs
+		requestID RequestIDConfig
+
+		// tracing enables the OpenTelemetry server spans when set
+		tracing *TracingConfig
+
+		// httpMetrics are the metrics shared by all the routes
+		httpMetrics *httpMetrics
+	}
+
+	// MetricsConfig configures the built-in HTTP metrics
+	MetricsConfig struct {
+
+		// Namespace is prepended to the metric names
+		// example: "shop" registers "shop_http_requests_total"
+		Namespace string
+
+		// Subsystem is added between the Namespace and the metric names
+		Subsystem string
+
+		// DurationBuckets are the response time histogram buckets in
+		// seconds, defaults to prometheus.DefBuckets
+		DurationBuckets []float64
+
+		// SizeBuckets are the request and response size histogram
+		// buckets in bytes, defaults to 100B to 10MB exponential buckets
+		SizeBuckets []float64
+
+		// Path the metrics endpoint is served on, defaults to "/metrics"
+		Path string
+
+		// Registerer registers the HTTP metrics, defaults to
+		// prometheus.DefaultRegisterer
+		Registerer prometheus.Registerer
+
+		// Gatherer collects the metrics served by the metrics endpoint,
+		// defaults to the Registerer when it is also a prometheus.Gatherer,
+		// such as a prometheus.Registry. Gatherer is required when the
+		// Registerer is not a prometheus.Gatherer, defaults to
+		// prometheus.DefaultGatherer without a Registerer
+		Gatherer prometheus.Gatherer
+
+		// DisableEndpoint removes the metrics endpoint from the router,
+		// the HTTP metrics are still recorded
+		DisableEndpoint bool
+
+		// Addr serves the metrics endpoint on a separate listener
+		// instead of the router, only used by Server
+		// example: "127.0.0.1:9090"
+		Addr string
+	}
+
+	// AccessLogFormat formats an AccessLogEntry into a single log line
+	AccessLogFormat func(entry *AccessLogEntry) string
+
+	// AccessLogEntry defines the request information available to an AccessLogFormat
+	AccessLogEntry struct {
+
+		// Time the request was received
+		Time time.Time
+
+		// RemoteIP is the X-Real-IP header or the connection remote address
+		RemoteIP string
+
+		// User is the basic authentication username
+		User string
+
+		// Method is the HTTP request method
+		Method string
+
+		// URI is the unmodified request URI
+		URI string
+
+		// Path is the request URL path
+		Path string
+
+		// Proto is the HTTP protocol version
+		Proto string
+
+		// Route is the name of the Route that handled the request
+		Route string
+
+		// Status is HTTP response status code
+		Status int
+
+		// Bytes is the size of the response body
+		Bytes int
+
+		// Duration is the time taken to serve the request
+		Duration time.Duration
+
+		// Referer is the Referer request header
+		Referer string
+
+		// UserAgent is the User-Agent request header
+		UserAgent string
+
+		// RequestID is the ID of the request, see RequestIDConfig
+		RequestID string
+
+		// TraceID is the trace of the request when tracing is enabled
+		TraceID string
+
+		// SpanID is the server span of the request when tracing is enabled
+		SpanID string
+	}
+
+	// RouterOption configures the router built by NewRouter
+	RouterOption func(*routerConfig)
+
 	response struct {
-		Code   int    `json:"code"`
-		Status string `json:"status"`
+		Code   int           `json:"code"`
+		Status string        `json:"status"`
+		Checks []checkResult `json:"checks,omitempty"`
+	}
+
+	checkResult struct {
+		Name     string `json:"name"`
+		Status   string `json:"status"`
+		Critical bool   `json:"critical"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration,omitempty"`
 	}
 )
 
+// encodingFailed is the body of a fallback response that fails to encode
+const encodingFailed = `{"code":500,"cause":"something went wrong, try again in few minutes"}`
+
 // Json encode ErrorResponse object for transmit.
 // returns a string
 func (e *ErrorResponse) Json() string {
 	body, err := json.Marshal(e)
 	if err != nil {
 		if e.looping {
-			log.Fatalln("error response looping detected")
+			slog.Error("error response looping detected", "error", err)
+			return encodingFailed
 		}
-		log.Printf("%#v", e)
+		slog.Error("encode error response", "code", e.Code, "error", err)
 		resp := ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Cause:   "something went wrong, try again in few minutes",
@@ -118,7 +815,7 @@ func (e *ErrorResponse) Json() string {
 func (r *response) json() string {
 	body, err := json.Marshal(r)
 	if err != nil {
-		log.Printf("%#v", r)
+		slog.Error("encode health response", "code", r.Code, "error", err)
 		resp := ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Cause:   "something went wrong, try again in few minutes",
@@ -130,9 +827,38 @@ func (r *response) json() string {
 	return string(body)
 }
 
+// routes returns the group routes with the Prefix
+// added to their Pattern
+func (g *RouteGroup) routes() Routes {
+	routes := make(Routes, 0, len(g.Routes))
+	for _, route := range g.Routes {
+		route.Pattern = g.Prefix + route.Pattern
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// conflict reports the first route that reuses the name or the
+// method and pattern of a route registered before it
 func (r *Routes) conflict() error {
-	if RouteTable != nil {
-		return errors.New("AddRoute method not allowed while RouteTable is set")
+	names := make(map[string]bool)
+	mappings := make(map[string]string)
+
+	for _, route := range *r {
+		if route.Name != "" {
+			if names[route.Name] {
+				return fmt.Errorf("route conflict: name %q is already registered", route.Name)
+			}
+			names[route.Name] = true
+		}
+
+		for _, method := range route.Methods {
+			key := method + " " + route.Pattern
+			if name, ok := mappings[key]; ok {
+				return fmt.Errorf("route conflict: %s ( [%s] %s ) is already registered by %s", route.Name, method, route.Pattern, name)
+			}
+			mappings[key] = route.Name
+		}
 	}
 
 	return nil
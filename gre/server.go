@@ -2,14 +2,20 @@ package gre
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -28,16 +34,19 @@ var (
 	// this allows users to append Route to from anywhere
 	// keeping the Route configurations and handler functions
 	// on the same file
-	RouteTable = Routes{
-		Route{
-			Name:        "Health",
-			Methods:     []string{http.MethodGet},
-			Pattern:     "/health",
-			HandlerFunc: health,
-		},
-	}
+	//
+	// RouteTable is not served by a Server unless it has been
+	// imported with Server.UseRouteTable
+	RouteTable = Routes{}
 )
 
+// defaultDrainTimeout is the time given to in-flight requests
+// to complete on Stop unless ShutdownConfig.DrainTimeout is set
+const defaultDrainTimeout = 5 * time.Second
+
+// metricsShutdownTimeout is the time given to metrics scrapes to complete on Stop
+const metricsShutdownTimeout = time.Second
+
 // NewServer returns a vanilla Server definition for later
 // configuration
 func NewServer() *Server {
@@ -66,70 +75,560 @@ func DefaultServer(port int, strictSlashes bool) *Server {
 //
 // param: <middleware> is http.Handler method
 func (s *Server) AddMiddleware(middleware func(http.Handler) http.Handler) *Server {
-	log.Printf("add middleware %#v", runtime.FuncForPC(reflect.ValueOf(middleware).Pointer()).Name())
 	s.middlewares = append(s.middlewares, middleware)
 	return s
 }
 
-// AddRoutes adds Routes to the server
+// AddRoutes adds Routes to the server's own route table
+//
+// param: <routes> is one or more Route
+func (s *Server) AddRoutes(routes ...Route) *Server {
+	s.routes = append(s.routes, routes...)
+	return s
+}
+
+// AddGroups adds RouteGroup to the server, each group is
+// registered on its own subrouter so the group middleware
+// only applies to the group routes
+//
+// param: <groups> is one or more RouteGroup
+func (s *Server) AddGroups(groups ...RouteGroup) *Server {
+	s.groups = append(s.groups, groups...)
+	return s
+}
+
+// UseRouteTable imports the package level RouteTable into the
+// server's routes when Build is invoked
+func (s *Server) UseRouteTable() *Server {
+	s.useRouteTable = true
+	return s
+}
+
+// Routes returns a copy of all the routes served by the server,
+// including the built-in routes, the imported RouteTable and the
+// group routes with their full path pattern
+func (s *Server) Routes() Routes {
+	routes := s.ungrouped()
+	for _, group := range s.groups {
+		routes = append(routes, group.routes()...)
+	}
+	return routes
+}
+
+// routesKey identifies the routes, groups and middleware served by the
+// handler built by Build
+func (s *Server) routesKey() string {
+	var key strings.Builder
+	for _, route := range s.Routes() {
+		fmt.Fprintf(&key, "%s %v %s\n", route.Name, route.Methods, route.Pattern)
+	}
+	fmt.Fprintf(&key, "groups=%d middlewares=%d strict=%t", len(s.groups), len(s.middlewares), s.StrictSlash)
+	return key.String()
+}
+
+func (s *Server) ungrouped() Routes {
+	routes, _ := s.builtinRoutes()
+	if s.useRouteTable {
+		routes = append(routes, RouteTable...)
+	}
+	return append(routes, s.routes...)
+}
+
+// builtinRoutes returns the built-in routes to serve and the ones
+// skipped because a route uses the same path, a route using the
+// name of a built-in route is reported as a conflict by Build
+func (s *Server) builtinRoutes() (Routes, Routes) {
+	builtin := Routes{s.healthRoute()}
+	if s.probes != nil {
+		builtin = append(builtin, s.probeRoute(LivenessProbe), s.probeRoute(ReadinessProbe), s.probeRoute(StartupProbe))
+	}
+	if s.openAPI != nil {
+		builtin = append(builtin, s.openAPIRoute())
+	}
+
+	var own Routes
+	if s.useRouteTable {
+		own = append(own, RouteTable...)
+	}
+	own = append(own, s.routes...)
+	for _, group := range s.groups {
+		own = append(own, group.routes()...)
+	}
+
+	var routes, skipped Routes
+	for _, route := range builtin {
+		if slices.ContainsFunc(own, func(r Route) bool {
+			return r.Pattern == route.Pattern
+		}) {
+			skipped = append(skipped, route)
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes, skipped
+}
+
+// AddOpenAPI serves an OpenAPI 3.1 document generated from the
+// server routes by Build on OpenAPIInfo.Path
+//
+// param: <info> is OpenAPIInfo definition for the document
+func (s *Server) AddOpenAPI(info OpenAPIInfo) *Server {
+	s.openAPI = &info
+	return s
+}
+
+// OpenAPI generates the OpenAPI 3.1 JSON document for all the
+// routes served by the server
+func (s *Server) OpenAPI() ([]byte, error) {
+	var info OpenAPIInfo
+	if s.openAPI != nil {
+		info = *s.openAPI
+	}
+
+	routes := s.Routes()
+	return routes.openAPI(info, s.config.problemDetails)
+}
+
+// UseLogger sets the slog.Logger used for route registration,
+// request logs and server lifecycle events, defaults to slog.Default
+//
+// param: <logger> is the slog.Logger for the server
+func (s *Server) UseLogger(logger *slog.Logger) *Server {
+	s.config.logger = logger
+	return s
+}
+
+// UseAccessLog writes request logs formatted by the AccessLogFormat
+// to the io.Writer instead of the server slog.Logger
+//
+// param: <format> is AccessLogFormat such as CommonLogFormat, CombinedLogFormat,
+// JSONLogFormat or TemplateLogFormat
 //
-// param: <routes> is list of Route wrapped in Routes
-func (s *Server) AddRoutes(route Route) *Server {
-	RouteTable = append(RouteTable, route)
+// param: <out> is the log destination, defaults to os.Stdout
+func (s *Server) UseAccessLog(format AccessLogFormat, out io.Writer) *Server {
+	s.config.accessLogFormat = format
+	s.config.accessLogOutput = out
+	return s
+}
+
+// UseMetrics configures the built-in HTTP metrics such as the
+// metric namespace and histogram buckets
+//
+// param: <metrics> is MetricsConfig definition of the metrics
+func (s *Server) UseMetrics(metrics MetricsConfig) *Server {
+	s.config.metrics = metrics
+	return s
+}
+
+// UseProblemDetails makes all framework generated errors such
+// as 404 and 405 respond with RFC 9457 ProblemDetails using
+// the application/problem+json content type
+func (s *Server) UseProblemDetails() *Server {
+	s.config.problemDetails = true
 	return s
 }
 
 // Build add all the provided configurations to the http.Server
 // definition from NewServer or DefaultServer
+//
+// Routes sharing the same name or the same method and pattern and
+// a metrics endpoint without a Gatherer are logged as invalid, the
+// error is returned by Listen and Run. Listen builds the server when
+// Build wasn't invoked or the routes changed since
 func (s *Server) Build() *Server {
-	s.Handler = addRoutes(RouteTable, s.StrictSlash)
+	routes := s.Routes()
+	s.buildErr = routes.conflict()
+	if metrics := s.config.metrics; !metrics.DisableEndpoint {
+		_, err := metrics.gatherer()
+		s.buildErr = errors.Join(s.buildErr, err)
+	}
+	if s.buildErr != nil {
+		s.config.log().Error("invalid server configuration", "error", s.buildErr)
+	}
+
+	_, skipped := s.builtinRoutes()
+	for _, route := range skipped {
+		s.config.log().Info("skip built-in mapping", "name", route.Name, "pattern", route.Pattern, "reason", "path in use")
+	}
+
+	cfg := s.config
+	cfg.strictSlash = s.StrictSlash
+	s.Handler = addRoutes(s.ungrouped(), s.groups, cfg)
 
 	for _, m := range s.middlewares {
+		s.config.log().Info("add middleware", "middleware", runtime.FuncForPC(reflect.ValueOf(m).Pointer()).Name())
 		s.addMiddleware(m)
 	}
+	s.addMiddleware(s.trackInFlight)
+	if err := s.configureHTTP2(); err != nil {
+		s.config.log().Error("invalid server configuration", "error", err)
+		s.buildErr = errors.Join(s.buildErr, err)
+	}
+	s.builtRoutes = s.routesKey()
+	if s.openAPI != nil {
+		s.openAPIDoc, s.openAPIErr = s.OpenAPI()
+	}
+
+	s.metricsServer = nil
+	if metrics := s.config.metrics; metrics.Addr != "" && !metrics.DisableEndpoint && s.buildErr == nil {
+		metricsHandler, _ := metrics.handler()
+		handler := http.NewServeMux()
+		handler.Handle(metrics.path(), requestLogger(newAccessLog(s.config.log(), s.config.accessLogFormat, s.config.accessLogOutput, s.config.requestID.header()), metricsHandler, "Prometheus metrics"))
+		s.metricsServer = &http.Server{
+			Addr:         metrics.Addr,
+			Handler:      handler,
+			ReadTimeout:  s.ReadTimeout,
+			WriteTimeout: s.WriteTimeout,
+		}
+		s.config.log().Info("add mapping", "name", "Prometheus metrics", "methods", []string{http.MethodGet}, "pattern", metrics.path(), "addr", metrics.Addr)
+	}
 	return s
 }
 
 // Start the http.Server daemon
 //
+// Errors binding or serving are logged and notify the returned channel
+// so that Stop can be invoked, use Listen or Run for handling errors.
+// The OnShutdown hooks are invoked before the signal is delivered, unless
+// the server failed to listen as the shutdown hooks only run once started.
+//
+// With UseUpgrade the upgrade signal is delivered once a new process
+// serving the listeners is ready
+//
 // returns chan os.Signal
 func (s *Server) Start() chan os.Signal {
-	log.Println("starting server daemon")
+	logger := s.config.log()
 
-	s.Build()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	upgraded, stopUpgrade := s.upgradeOnSignal()
 
 	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	failed := make(chan struct{})
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case sig = <-upgraded:
+		case <-failed:
+			signal.Stop(signals)
+			stopUpgrade()
+			shutdown <- syscall.SIGTERM
+			return
+		}
+		signal.Stop(signals)
+		stopUpgrade()
+		_ = s.beginShutdown()
+		shutdown <- sig
+	}()
+
+	errs, err := s.Listen()
+	if err != nil {
+		logger.Error("server daemon failed", "error", err)
+		close(failed)
+		return shutdown
+	}
+
 	go func() {
-		if err := s.ListenAndServe(); err != nil {
-			log.Fatalf("%s", err.Error())
+		for err := range errs {
+			logger.Error("server daemon failed", "error", err)
+			select {
+			case signals <- syscall.SIGTERM:
+			default:
+			}
 		}
 	}()
 
 	return shutdown
 }
 
-// Stop the http.Server daemon
+// Listen binds the server address and listeners and serves the requests
+// in the background. Errors binding the address are returned, while errors
+// serving the requests are sent on the returned channel which is
+// closed once the server has stopped
+//
+// returns <-chan error and the bind error
+func (s *Server) Listen() (<-chan error, error) {
+	logger := s.config.log()
+	logger.Info("starting server daemon", "addr", s.Addr, "tls", s.tls != nil)
+
+	// the server built by the caller is only rebuilt when its routes changed
+	if s.builtRoutes == "" || s.builtRoutes != s.routesKey() {
+		s.Build()
+	}
+	if s.buildErr != nil {
+		return nil, s.buildErr
+	}
+
+	// a server stopped before is no longer draining
+	s.draining.Store(false)
+	s.started.Store(false)
+	s.shutdownOnce = sync.Once{}
+	s.shutdownErr = nil
+
+	// a process started by Upgrade serves the inherited listeners
+	handoff, err := inheritedListeners()
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) (<-chan error, error) {
+		handoff.close()
+		return nil, err
+	}
+
+	var certs *certReloader
+	var tlsConfig *tls.Config
+	if s.tls != nil {
+		if certs, err = newCertReloader(*s.tls, logger); err != nil {
+			return fail(err)
+		}
+		tlsConfig = certs.tlsConfig(s.TLSConfig)
+	}
+	s.certs = certs
+
+	addr := ":http"
+	if certs != nil {
+		addr = ":https"
+	}
+	var listeners []net.Listener
+	if handoff != nil && len(handoff.listeners) > 0 {
+		s.checkInherited(handoff, addr)
+		listeners = handoff.listeners
+	} else if listeners, err = s.listen(addr); err != nil {
+		return fail(err)
+	}
+	s.bound = listeners
+
+	var metricsListener net.Listener
+	if s.metricsServer != nil {
+		logger.Info("starting metrics daemon", "addr", s.metricsServer.Addr)
+		if handoff != nil && handoff.metrics != nil {
+			metricsListener = handoff.metrics
+		} else if metricsListener, err = net.Listen("tcp", s.metricsServer.Addr); err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fail(err)
+		}
+	}
+	s.metricsBound = metricsListener
+
+	// the OnStopped hooks undo the OnStart hooks when a hook fails
+	stopped := func(err error) (<-chan error, error) {
+		ctx, cancel := s.hookContext()
+		defer cancel()
+		return fail(errors.Join(err, s.runHooks(ctx, "stopped", s.hooks.stopped, true)))
+	}
+
+	if err := s.runHooks(context.Background(), "start", s.hooks.start, false); err != nil {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+		if metricsListener != nil {
+			_ = metricsListener.Close()
+		}
+		return stopped(err)
+	}
+
+	if certs != nil {
+		var ctx context.Context
+		ctx, s.stopWatch = context.WithCancel(context.Background())
+		go certs.watch(ctx)
+	}
+
+	errs := make(chan error, len(listeners)+1)
+	var wg sync.WaitGroup
+	// http.Server refuses to serve once it has been shut down, which
+	// is only expected while draining
+	serve := func(server *http.Server, listener net.Listener) {
+		defer wg.Done()
+		if err := server.Serve(listener); err != nil && (!errors.Is(err, http.ErrServerClosed) || !s.Draining()) {
+			errs <- err
+		}
+	}
+
+	for _, listener := range listeners {
+		logger.Info("listening", "network", listener.Addr().Network(), "addr", listener.Addr().String())
+		if certs != nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+		wg.Add(1)
+		go serve(&s.Server, listener)
+	}
+	if metricsListener != nil {
+		wg.Add(1)
+		go serve(s.metricsServer, metricsListener)
+	}
+
+	go func() {
+		wg.Wait()
+		close(errs)
+	}()
+
+	if err := s.runHooks(context.Background(), "ready", s.hooks.ready, false); err != nil {
+		if s.stopWatch != nil {
+			s.stopWatch()
+		}
+		_ = s.Close()
+		if s.metricsServer != nil {
+			_ = s.metricsServer.Close()
+		}
+		return stopped(err)
+	}
+	s.started.Store(true)
+	handoff.notifyReady()
+
+	return errs, nil
+}
+
+// Run serves the requests until the context is done, the server
+// fails or it has been upgraded to a new process, then stops the
+// server gracefully
+//
+// param: <ctx> is the context controlling the server lifetime
+//
+// returns bind, serve or shutdown error
+func (s *Server) Run(ctx context.Context) error {
+	errs, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	upgraded, stopUpgrade := s.upgradeOnSignal()
+	defer stopUpgrade()
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errs:
+	case <-upgraded:
+	}
+
+	return errors.Join(serveErr, s.Stop())
+}
+
+// Stop the http.Server daemon gracefully
+//
+// The /health endpoint fails for ShutdownConfig.PreShutdownDelay before the
+// listeners are closed, then in-flight requests are given the DrainTimeout
+// to complete. A DrainTimeoutError is returned when requests are still in
+// flight at the deadline, and their connections are closed.
+//
+// The OnShutdown hooks are invoked first unless a shutdown signal already
+// invoked them, and the OnStopped hooks once the server has shut down.
+// The hooks are skipped when the server failed to start listening
 //
 // returns shutdown error
 func (s *Server) Stop() error {
-	log.Println("stopping server daemon")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	logger := s.config.log()
+	// the hooks only run once for a server that started listening
+	started := s.started.Swap(false)
+	var hookErr error
+	if started {
+		hookErr = s.beginShutdown()
+	}
+	logger.Info("stopping server daemon", "addr", s.Addr)
+
+	s.draining.Store(true)
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+	if delay := s.shutdown.PreShutdownDelay; delay > 0 && started {
+		logger.Info("failing health checks before shutdown", "delay", delay)
+		time.Sleep(delay)
+	}
+
+	timeout := s.shutdown.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer func() {
 		cancel()
 	}()
 
-	return s.Shutdown(ctx)
+	err := s.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = &DrainTimeoutError{Timeout: timeout, InFlight: s.InFlight()}
+		logger.Warn("drain timeout exceeded", "timeout", timeout, "in_flight", s.InFlight())
+		_ = s.Close()
+	}
+
+	// the metrics server gets its own timeout as the drain may have used up ctx
+	if s.metricsServer != nil {
+		metricsCtx, metricsCancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		metricsErr := s.metricsServer.Shutdown(metricsCtx)
+		metricsCancel()
+		if metricsErr != nil {
+			_ = s.metricsServer.Close()
+			if err == nil {
+				err = metricsErr
+			}
+		}
+	}
+
+	if !started {
+		return err
+	}
+
+	hookCtx, hookCancel := s.hookContext()
+	defer hookCancel()
+
+	return errors.Join(hookErr, err, s.runHooks(hookCtx, "stopped", s.hooks.stopped, true))
+}
+
+// UseShutdown configures the graceful shutdown performed by Stop
+//
+// param: <shutdown> is ShutdownConfig definition of the shutdown
+func (s *Server) UseShutdown(shutdown ShutdownConfig) *Server {
+	s.shutdown = shutdown
+	return s
+}
+
+// InFlight returns the number of requests currently being served
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// Draining reports whether Stop has been invoked since the server
+// last started listening
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// Error returns the DrainTimeoutError message
+func (e *DrainTimeoutError) Error() string {
+	return fmt.Sprintf("drain timeout of %s exceeded with %d requests in flight", e.Timeout, e.InFlight)
+}
+
+// Unwrap returns context.DeadlineExceeded
+func (e *DrainTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+func (s *Server) trackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
 }
 
 // AddCORSHandler is pre-defined CORS configuration that
 // is used to configure CORS without needing for a custom http.Handler
 //
+// The origin is set on every response and OPTIONS requests are answered
+// with the allowed methods and headers, the ContextType is applied
+// with UseResponseDefaults
+//
+// Deprecated: use UseCORS for CORS and UseResponseDefaults for the content type
+//
 // param: <handlerConfig> is HttpResponseConfig definition for CORS config
 func (s *Server) AddCORSHandler(handlerConfig HttpResponseConfig) *Server {
+	s.config.corsMethods = true
 	s.AddMiddleware(func(next http.Handler) http.Handler {
 		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", handlerConfig.ContextType)
 			w.Header().Set("Access-Control-Allow-Origin", handlerConfig.AccessControlAllowOrigin)
 			if r.Method == http.MethodOptions {
 				w.Header().Set("Access-Control-Allow-Methods", strings.Join(handlerConfig.AccessControlAllowMethods, ","))
@@ -141,6 +640,10 @@ func (s *Server) AddCORSHandler(handlerConfig HttpResponseConfig) *Server {
 		})
 		return h
 	})
+
+	if handlerConfig.ContextType != "" {
+		s.config.defaults.ContentType = handlerConfig.ContextType
+	}
 	return s
 }
 
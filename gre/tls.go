@@ -0,0 +1,209 @@
+package gre
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+// certReloader serves the certificates loaded from disk and reloads
+// them when the files change
+type certReloader struct {
+	config TLSConfig
+	logger *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// UseTLS serves HTTPS using the certificate files of the TLSConfig,
+// which are reloaded when they change on disk
+//
+// param: <config> is TLSConfig definition of the TLS server
+func (s *Server) UseTLS(config TLSConfig) *Server {
+	s.tls = &config
+	return s
+}
+
+func newCertReloader(config TLSConfig, logger *slog.Logger) (*certReloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("tls: CertFile and KeyFile are required")
+	}
+
+	reloader := &certReloader{config: config, logger: logger}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// ReloadTLS reloads the certificate files of the TLSConfig without
+// waiting for the ReloadInterval, the previous certificates are kept
+// when the reload fails. It is a no-op until the HTTPS server is listening
+func (s *Server) ReloadTLS() error {
+	if s.certs == nil {
+		return nil
+	}
+	return s.certs.reload()
+}
+
+// tlsConfig returns a clone of the base tls.Config serving the current
+// certificates, the TLSConfig options override the base when set
+//
+// param: <base> is the http.Server TLSConfig set by the user, nil when not set
+func (c *certReloader) tlsConfig(base *tls.Config) *tls.Config {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if c.config.MinVersion != 0 {
+		config.MinVersion = c.config.MinVersion
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if c.config.CipherSuites != nil {
+		config.CipherSuites = c.config.CipherSuites
+	}
+	if c.config.ClientAuth != tls.NoClientCert {
+		config.ClientAuth = c.config.ClientAuth
+	}
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{"h2", "http/1.1"}
+	}
+
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.cert, nil
+	}
+
+	if c.config.ClientCAFile == "" {
+		return config
+	}
+	if config.ClientAuth == tls.NoClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	client := config.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		config := client.Clone()
+		config.ClientCAs = c.clientCAs
+		return config, nil
+	}
+	return config
+}
+
+// load reads the certificate, key and client CA files
+func (c *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls: loading key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: reading client CA: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates found in %s", c.config.ClientCAFile)
+		}
+	}
+
+	modTimes, err := c.stat()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.clientCAs = clientCAs
+	c.modTimes = modTimes
+	return nil
+}
+
+func (c *certReloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{c.config.CertFile, c.config.KeyFile, c.config.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// changed reports whether any of the files was modified since the last load
+func (c *certReloader) changed() bool {
+	modTimes, err := c.stat()
+	if err != nil {
+		c.logger.Error("tls certificate check failed", "error", err)
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for file, modTime := range modTimes {
+		if !modTime.Equal(c.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch reloads the certificates when the files change until the
+// context is done, the previous certificates are kept when a reload fails
+func (c *certReloader) watch(ctx context.Context) {
+	interval := c.config.ReloadInterval
+	if interval == 0 {
+		interval = defaultReloadInterval
+	}
+	if interval < 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.changed() {
+				_ = c.reload()
+			}
+		}
+	}
+}
+
+// reload loads the certificates, logging the result
+func (c *certReloader) reload() error {
+	if err := c.load(); err != nil {
+		c.logger.Error("tls certificate reload failed", "error", err)
+		return err
+	}
+	c.logger.Info("tls certificate reloaded", "cert", c.config.CertFile)
+	return nil
+}
@@ -0,0 +1,160 @@
+package gre
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+const tracerName = "github.com/razorcorp/go-routing-engine/gre"
+
+// tracing starts the server spans of a router
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// UseTracing enables OpenTelemetry server spans for all the routes,
+// joining the trace context extracted from the request headers
+//
+// param: <config> is TracingConfig definition of the tracer and propagator
+func (s *Server) UseTracing(config TracingConfig) *Server {
+	s.config.tracing = &config
+	return s
+}
+
+// WithTracing enables OpenTelemetry server spans for the router routes
+//
+// param: <config> is TracingConfig definition of the tracer and propagator
+func WithTracing(config TracingConfig) RouterOption {
+	return func(c *routerConfig) {
+		c.tracing = &config
+	}
+}
+
+// newTracing returns the tracing of the config, nil when tracing is disabled
+func newTracing(config *TracingConfig) *tracing {
+	if config == nil {
+		return nil
+	}
+
+	provider := config.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	propagator := config.Propagator
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	return &tracing{tracer: provider.Tracer(tracerName), propagator: propagator}
+}
+
+// middleware serves the request within a server span, 5xx responses
+// and panics set the span status to error
+func (t *tracing) middleware(next http.Handler) http.Handler {
+	if t == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		name, attrs := spanName(r)
+		ctx, span := t.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer func() {
+			if value := recover(); value != nil {
+				span.SetStatus(codes.Error, fmt.Sprint(value))
+				span.End()
+				panic(value)
+			}
+			span.End()
+		}()
+
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// spanName returns the Route.Name of the matched route, otherwise the
+// method and route path template, along with the request attributes
+// example: "GET /user/{name}"
+func spanName(r *http.Request) (string, []attribute.KeyValue) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(methodLabel(r.Method)),
+		semconv.URLScheme(scheme),
+		semconv.URLPath(r.URL.Path),
+		semconv.ServerAddress(r.Host),
+		semconv.NetworkProtocolVersion(fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+	}
+	if agent := r.UserAgent(); agent != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(agent))
+	}
+
+	method := methodLabel(r.Method)
+	if method == "OTHER" {
+		method = "HTTP"
+	}
+
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return method, attrs
+	}
+	template, err := route.GetPathTemplate()
+	if err == nil {
+		attrs = append(attrs, semconv.HTTPRoute(template))
+	}
+	if name := route.GetName(); name != "" {
+		return name, attrs
+	}
+	if err != nil {
+		return method, attrs
+	}
+	return method + " " + template, attrs
+}
+
+// recordPanic adds the recovered panic to the span of the request
+func recordPanic(ctx context.Context, report *PanicReport) {
+	trace.SpanFromContext(ctx).RecordError(fmt.Errorf("panic: %v", report.Value),
+		trace.WithAttributes(semconv.ExceptionStacktraceKey.String(string(report.Stack))),
+	)
+}
+
+// spanIDs returns the trace and span IDs of the request span,
+// empty strings when the request is not traced
+func spanIDs(ctx context.Context) (string, string) {
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsValid() {
+		return "", ""
+	}
+	return span.TraceID().String(), span.SpanID().String()
+}
+
+// exemplar returns the trace_id exemplar of sampled requests, otherwise nil
+func exemplar(ctx context.Context) prometheus.Labels {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": spanContext.TraceID().String()}
+}
@@ -0,0 +1,322 @@
+package gre
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	upgradeFdsEnv     = "GRE_LISTEN_FDS"
+	upgradeFdNamesEnv = "GRE_LISTEN_FDNAMES"
+	upgradeReadyEnv   = "GRE_UPGRADE_READY_FD"
+
+	serverListenerName  = "http"
+	metricsListenerName = "metrics"
+
+	defaultReadyTimeout = 30 * time.Second
+)
+
+// handoff holds the listeners inherited from the process being upgraded
+// and the pipe used to report ready to it
+type handoff struct {
+	listeners []net.Listener
+	metrics   net.Listener
+	ready     *os.File
+}
+
+// UseUpgrade enables zero-downtime upgrades on the UpgradeConfig signals,
+// see Server.Upgrade. Start returns the signal on its channel and Run
+// returns once the new process is ready so the server can be stopped
+//
+// param: <config> is UpgradeConfig definition of the upgrade
+func (s *Server) UseUpgrade(config UpgradeConfig) *Server {
+	s.upgrade = &config
+	return s
+}
+
+// Upgrade starts a new process of the executable with the same arguments,
+// passing the server listeners as inherited file descriptors, and waits for
+// it to report ready once its OnReady hooks have completed. Both processes
+// serve the listeners until this one is stopped.
+//
+// The new process is killed when it isn't ready within the ReadyTimeout,
+// and this process keeps serving
+//
+// returns upgrade error
+func (s *Server) Upgrade() error {
+	if !s.started.Load() || s.Draining() {
+		return errors.New("upgrade: server is not serving")
+	}
+	if !s.upgrading.CompareAndSwap(false, true) {
+		return errors.New("upgrade: already in progress")
+	}
+	defer s.upgrading.Store(false)
+
+	logger := s.config.log()
+
+	listeners := append([]net.Listener{}, s.bound...)
+	names := make([]string, 0, len(listeners)+1)
+	for range s.bound {
+		names = append(names, serverListenerName)
+	}
+	if s.metricsBound != nil {
+		listeners = append(listeners, s.metricsBound)
+		names = append(names, metricsListenerName)
+	}
+
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	for _, l := range listeners {
+		filer, ok := l.(interface{ File() (*os.File, error) })
+		if !ok {
+			closeFiles()
+			return fmt.Errorf("upgrade: listener %s can't be inherited", l.Addr())
+		}
+		f, err := filer.File()
+		if err != nil {
+			closeFiles()
+			return fmt.Errorf("upgrade: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		closeFiles()
+		return fmt.Errorf("upgrade: %w", err)
+	}
+	defer ready.Close()
+	files = append(files, readyWriter)
+
+	path, err := os.Executable()
+	if err != nil {
+		closeFiles()
+		return fmt.Errorf("upgrade: %w", err)
+	}
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		upgradeFdsEnv+"="+strconv.Itoa(len(listeners)),
+		upgradeFdNamesEnv+"="+strings.Join(names, ":"),
+		upgradeReadyEnv+"="+strconv.Itoa(listenFdsStart+len(listeners)),
+	)
+
+	err = cmd.Start()
+	for _, f := range files[:len(listeners)] {
+		restoreNonblock(f)
+	}
+	closeFiles()
+	if err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+	logger.Info("upgrade started", "pid", cmd.Process.Pid)
+
+	if err := waitReady(ready, s.upgradeReadyTimeout()); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		logger.Error("upgrade failed", "pid", cmd.Process.Pid, "error", err)
+		return fmt.Errorf("upgrade: %w", err)
+	}
+
+	// the socket files are served by the new process from now on
+	for _, l := range s.bound {
+		if unix, ok := l.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false)
+		}
+	}
+
+	logger.Info("upgrade completed", "pid", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+func (s *Server) upgradeReadyTimeout() time.Duration {
+	if s.upgrade == nil || s.upgrade.ReadyTimeout <= 0 {
+		return defaultReadyTimeout
+	}
+	return s.upgrade.ReadyTimeout
+}
+
+// upgradeOnSignal upgrades the server on the upgrade signals, the
+// returned channel receives the signal once a new process is ready
+//
+// returns the upgrade channel and the function to stop watching signals
+func (s *Server) upgradeOnSignal() (<-chan os.Signal, func()) {
+	upgraded := make(chan os.Signal, 1)
+	if s.upgrade == nil {
+		return upgraded, func() {}
+	}
+
+	signals := s.upgrade.Signals
+	if len(signals) == 0 {
+		signals = defaultUpgradeSignals
+	}
+	if len(signals) == 0 {
+		return upgraded, func() {}
+	}
+
+	notify := make(chan os.Signal, 1)
+	signal.Notify(notify, signals...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-notify:
+				s.config.log().Info("upgrade requested", "signal", sig.String())
+				if err := s.Upgrade(); err != nil {
+					continue
+				}
+				upgraded <- sig
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return upgraded, func() {
+		once.Do(func() {
+			signal.Stop(notify)
+			close(done)
+		})
+	}
+}
+
+// waitReady waits for the new process to write to the ready pipe,
+// the pipe is closed without a write when the process exits
+func waitReady(ready *os.File, timeout time.Duration) error {
+	_ = ready.SetReadDeadline(time.Now().Add(timeout))
+
+	b := make([]byte, 1)
+	if _, err := ready.Read(b); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("new process exited before it was ready")
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return fmt.Errorf("new process not ready after %s", timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// inheritedListeners returns the listeners passed by the process being
+// upgraded, or nil when the process was not started by an upgrade
+func inheritedListeners() (*handoff, error) {
+	fds, ok := os.LookupEnv(upgradeFdsEnv)
+	if !ok {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv(upgradeFdNamesEnv), ":")
+	readyFd := os.Getenv(upgradeReadyEnv)
+	_ = os.Unsetenv(upgradeFdsEnv)
+	_ = os.Unsetenv(upgradeFdNamesEnv)
+	_ = os.Unsetenv(upgradeReadyEnv)
+
+	h := &handoff{}
+	if fd, err := strconv.Atoi(readyFd); err == nil {
+		h.ready = os.NewFile(uintptr(fd), "ready")
+	}
+
+	count, err := strconv.Atoi(fds)
+	if err != nil {
+		h.close()
+		return nil, fmt.Errorf("upgrade: invalid %s: %w", upgradeFdsEnv, err)
+	}
+
+	listeners, err := fileListeners(listenFdsStart, count, names)
+	if err != nil {
+		h.close()
+		return nil, fmt.Errorf("upgrade: %w", err)
+	}
+
+	for i, l := range listeners {
+		if i < len(names) && names[i] == metricsListenerName {
+			h.metrics = l
+			continue
+		}
+		h.listeners = append(h.listeners, l)
+	}
+	return h, nil
+}
+
+// checkInherited logs a warning for each configured listener without an
+// inherited listener and each inherited listener that isn't configured,
+// such as when the new executable changed the listener configuration.
+// The inherited listeners are served regardless
+func (s *Server) checkInherited(h *handoff, defaultAddr string) {
+	logger := s.config.log()
+
+	if s.metricsServer != nil && h.metrics != nil && !(ListenerConfig{Address: s.metricsServer.Addr}).matches(h.metrics.Addr()) {
+		logger.Warn("configured listener not inherited", "network", "tcp", "addr", s.metricsServer.Addr)
+	}
+
+	// the sockets passed by systemd replace the configured listeners
+	// and have no configuration to match
+	if s.systemd {
+		return
+	}
+
+	matched := make([]bool, len(h.listeners))
+	for _, config := range s.listenerConfigs(defaultAddr) {
+		found := false
+		for i, l := range h.listeners {
+			if !matched[i] && config.matches(l.Addr()) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			logger.Warn("configured listener not inherited", "network", config.network(), "addr", config.Address)
+		}
+	}
+
+	for i, l := range h.listeners {
+		if !matched[i] {
+			logger.Warn("inherited listener not configured", "network", l.Addr().Network(), "addr", l.Addr().String())
+		}
+	}
+}
+
+// notifyReady reports ready to the process being upgraded
+func (h *handoff) notifyReady() {
+	if h == nil || h.ready == nil {
+		return
+	}
+	_, _ = h.ready.Write([]byte{1})
+	_ = h.ready.Close()
+}
+
+// close releases the inherited listeners and the ready pipe,
+// the process being upgraded keeps serving
+func (h *handoff) close() {
+	if h == nil {
+		return
+	}
+	for _, l := range h.listeners {
+		_ = l.Close()
+	}
+	if h.metrics != nil {
+		_ = h.metrics.Close()
+	}
+	if h.ready != nil {
+		_ = h.ready.Close()
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package gre
+
+import "os"
+
+// defaultUpgradeSignals is empty as SIGHUP and SIGUSR2 are not
+// delivered on this platform, UpgradeConfig.Signals must be set
+var defaultUpgradeSignals []os.Signal
+
+func restoreNonblock(*os.File) {}
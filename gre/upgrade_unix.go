@@ -0,0 +1,17 @@
+//go:build unix
+
+package gre
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultUpgradeSignals start an upgrade unless UpgradeConfig.Signals is set
+var defaultUpgradeSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// restoreNonblock reverts the socket shared with the listener to non-blocking
+// mode, which is cleared when the file is passed to the new process
+func restoreNonblock(f *os.File) {
+	_ = syscall.SetNonblock(int(f.Fd()), true)
+}